@@ -12,22 +12,40 @@ import (
 	"time"
 )
 
+const (
+	refreshDelay = 5 * time.Second
+	countdown    = time.Second
+	minBackoff   = time.Second
+	maxBackoff   = time.Minute
+)
+
 type Model struct {
-	id   int
-	info docker.ServerInfo
+	id        int
+	info      docker.ServerInfo
+	connected bool
+	err       error
+	backoff   time.Duration
+	retry     time.Time
 }
 
 var titleStyle = lipgloss.NewStyle().
 	Bold(false).
 	Foreground(lipgloss.Color("70"))
 
+var errorStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.Color("196"))
+
 var valueStyle = lipgloss.NewStyle().
 	Bold(true).
 	AlignHorizontal(lipgloss.Right).
 	Foreground(lipgloss.Color("255"))
 
 func (m Model) tick() tea.Cmd {
-	delay := 5 * time.Second
+	delay := refreshDelay
+	if !m.connected {
+		delay = countdown
+	}
 
 	return tea.Tick(delay, func(t time.Time) tea.Msg {
 		return timers.TimerMsg{ID: m.id, Tag: t, Timeout: false}
@@ -36,32 +54,110 @@ func (m Model) tick() tea.Cmd {
 
 func (m Model) Init() tea.Cmd {
 	logger.Trace()
+	if !m.connected {
+		change := docker.ConnectionMsg{Connected: false, Err: m.err}
+		return tea.Batch(m.tick(), func() tea.Msg { return change })
+	}
 	return m.tick()
 }
 
+// StatusMsg carries the answer of the engine to a poll.
+type StatusMsg struct {
+	id   int
+	info docker.ServerInfo
+	err  error
+}
+
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	logger.Trace(msg)
 
 	switch msg := msg.(type) {
 	case timers.TimerMsg:
-		if msg.ID == m.id {
-			status, err := docker.Info()
-			if err != nil {
-				log.Println(err)
-			}
-			m.info = status
+		if msg.ID != m.id {
+			return m, nil
+		}
+		if m.connected || !time.Now().Before(m.retry) {
+			return m, m.poll()
 		}
+		return m, m.tick()
+	case StatusMsg:
+		if msg.id != m.id {
+			return m, nil
+		}
+		return m.refresh(msg.info, msg.err)
 	case tea.WindowSizeMsg:
 		m.resize(msg.Width, msg.Height)
 	}
 
-	return m, m.tick()
+	return m, nil
+}
+
+// poll asks the engine for its status in the background. Once it has gone
+// away, the engine is detected again, probing every candidate socket.
+func (m Model) poll() tea.Cmd {
+	id := m.id
+	reset := !m.connected
+	return func() tea.Msg {
+		if reset {
+			docker.Reset()
+		}
+		status, err := docker.Info()
+		return StatusMsg{id: id, info: status, err: err}
+	}
+}
+
+// refresh takes in the result of a poll, switching between the connected
+// and disconnected states. While disconnected, attempts back off
+// exponentially.
+func (m Model) refresh(status docker.ServerInfo, err error) (Model, tea.Cmd) {
+	wasConnected := m.connected
+
+	if err != nil {
+		log.Println(err)
+
+		m.connected = false
+		m.err = err
+		m.backoff = min(max(2*m.backoff, minBackoff), maxBackoff)
+		m.retry = time.Now().Add(m.backoff)
+	} else {
+		m.connected = true
+		m.err = nil
+		m.backoff = 0
+		m.info = status
+	}
+
+	cmds := []tea.Cmd{m.tick()}
+	if wasConnected != m.connected {
+		logger.Info("Engine connected: ", m.connected)
+		change := docker.ConnectionMsg{Connected: m.connected, Err: err}
+		cmds = append(cmds, func() tea.Msg { return change })
+	}
+
+	return m, tea.Batch(cmds...)
 }
 
 func (m Model) View() string {
 	var s string
+	if !m.connected {
+		wait := max(time.Until(m.retry).Round(time.Second), 0)
+		reason := ""
+		if m.err != nil {
+			reason = m.err.Error()
+		}
+
+		s += lipgloss.JoinVertical(lipgloss.Top,
+			displayField("Server:    ", m.info.Name),
+			fmt.Sprintf("%s %s", titleStyle.Render("Engine:    "), errorStyle.Render("disconnected")),
+			displayField("Error:     ", reason),
+			displayField("Retry in:  ", wait.String()),
+			displayField("Backoff:   ", m.backoff.String()),
+		)
+		return s
+	}
+
 	s += lipgloss.JoinVertical(lipgloss.Top,
 		displayField("Server:    ", m.info.Name),
+		displayField("Engine:    ", fmt.Sprintf("%s / API %s", m.info.Engine, m.info.APIVersion)),
 		displayField("Server Ver:", m.info.ServerVersion),
 		displayField("Client Ver:", m.info.ClientVersion),
 		displayField("Images:    ", strconv.Itoa(m.info.Images)),
@@ -85,14 +181,12 @@ func (m *Model) resize(width int, height int) {
 }
 
 func New() Model {
-	status, err := docker.Info()
-	if err != nil {
-		log.Println(err)
-	}
-
 	m := Model{
-		id:   timers.NextID(),
-		info: status,
+		id:        timers.NextID(),
+		connected: true,
 	}
+	status, err := docker.Info()
+	m, _ = m.refresh(status, err)
+
 	return m
 }
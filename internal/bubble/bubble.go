@@ -2,17 +2,42 @@
 package bubble
 
 import (
+	"fmt"
+	"regexp"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/mattn/go-runewidth"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
-	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/bubbles/textinput"
 )
 
+// FilterMode selects how the filter query is matched against the cells.
+type FilterMode int
+
+const (
+	FilterSubstring FilterMode = iota
+	FilterFuzzy
+	FilterRegex
+	filterModes
+)
+
+func (f FilterMode) String() string {
+	switch f {
+	case FilterFuzzy:
+		return "fuzzy"
+	case FilterRegex:
+		return "regex"
+	}
+	return "substring"
+}
+
 // Model defines a state for the table widget.
 type Model struct {
 	KeyMap KeyMap
@@ -24,9 +49,43 @@ type Model struct {
 	focus  bool
 	styles Styles
 
-	viewport viewport.Model
-	start    int
-	end      int
+	// viewWidth and viewHeight bound what is shown of the headers and
+	// rows, content is that part rendered
+	viewWidth  int
+	viewHeight int
+	content    string
+	start      int
+	end        int
+	height     int
+	// rendered caches the rows drawn by index in rows, header the
+	// headers, until invalidate
+	rendered map[int]string
+	header   string
+
+	// scrollX is how far the table is scrolled to the right
+	scrollX int
+
+	// all holds every row, rows only those passing the filter, visible
+	// maps the latter back to the former and matches holds the matched
+	// rune positions of every cell of rows
+	all       []Row
+	visible   []int
+	matches   [][][]int
+	filter    textinput.Model
+	filtering bool
+	mode      FilterMode
+	filterErr error
+
+	// marks are keyed by the first column so they survive a refresh
+	marks map[string]bool
+
+	sortCol  int
+	sortDesc bool
+
+	// widths the columns are drawn at, see layout, and the order they are
+	// drawn in, nil for all of them as they are
+	widths []int
+	order  []int
 }
 
 // Row represents one line in the table.
@@ -35,7 +94,36 @@ type Row []string
 // Column defines the table structure.
 type Column struct {
 	Title string
-	Width int
+	// Width is the display width of the widest value, MinWidth and MaxWidth
+	// bound it, Flex lets the column give up room when the table does not
+	// fit and Hide drops it altogether, the highest first, 0 never
+	Width    int
+	MinWidth int
+	MaxWidth int
+	Flex     int
+	Hide     int
+	Type     ColumnType
+	// Format turns the raw value held in the rows into the one shown,
+	// sorting and filtering work on the raw value
+	Format func(string) string
+	// Highlight styles parts of the value shown, filter matches standing
+	// out over them
+	Highlight func(string) []Span
+}
+
+// Span styles the runes of a value from Start up to End.
+type Span struct {
+	Start int
+	End   int
+	Style lipgloss.Style
+}
+
+// Display returns the value as shown in the column.
+func (c Column) Display(value string) string {
+	if c.Format == nil {
+		return value
+	}
+	return c.Format(value)
 }
 
 // KeyMap defines keybindings. It satisfies to the help.KeyMap interface, which
@@ -51,6 +139,13 @@ type KeyMap struct {
 	GotoBottom   key.Binding
 	ScrollLeft   key.Binding
 	ScrollRight  key.Binding
+	Filter       key.Binding
+	FilterAccept key.Binding
+	FilterCancel key.Binding
+	FilterMode   key.Binding
+	Mark         key.Binding
+	MarkAll      key.Binding
+	MarkInvert   key.Binding
 }
 
 // ShortHelp implements the KeyMap interface.
@@ -63,6 +158,8 @@ func (km KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{km.LineUp, km.LineDown, km.GotoTop, km.GotoBottom},
 		{km.PageUp, km.PageDown, km.HalfPageUp, km.HalfPageDown},
+		{km.Filter, km.FilterAccept, km.FilterCancel, km.FilterMode},
+		{km.Mark, km.MarkAll, km.MarkInvert},
 	}
 }
 
@@ -83,7 +180,7 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("b/pgup", "page up"),
 		),
 		PageDown: key.NewBinding(
-			key.WithKeys("f", "pgdown", spacebar),
+			key.WithKeys("f", "pgdown"),
 			key.WithHelp("f/pgdn", "page down"),
 		),
 		HalfPageUp: key.NewBinding(
@@ -110,6 +207,34 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("right", "l"),
 			key.WithHelp("→/l", ">>>>"),
 		),
+		Filter: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "filter"),
+		),
+		FilterAccept: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "apply filter"),
+		),
+		FilterCancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "clear filter"),
+		),
+		FilterMode: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "substring/fuzzy/regex"),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys(spacebar, "x"),
+			key.WithHelp("space/x", "mark"),
+		),
+		MarkAll: key.NewBinding(
+			key.WithKeys("ctrl+a"),
+			key.WithHelp("ctrl+a", "mark all"),
+		),
+		MarkInvert: key.NewBinding(
+			key.WithKeys("*"),
+			key.WithHelp("*", "invert marks"),
+		),
 	}
 }
 
@@ -119,6 +244,9 @@ type Styles struct {
 	Header   lipgloss.Style
 	Cell     func(Row) lipgloss.Style
 	Selected lipgloss.Style
+	Match    lipgloss.Style
+	Filter   lipgloss.Style
+	Marked   lipgloss.Style
 }
 
 // DefaultStyles returns a set of default style definitions for this table.
@@ -126,6 +254,9 @@ func DefaultStyles() Styles {
 	return Styles{
 		Selected: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212")),
 		Header:   lipgloss.NewStyle().Bold(true).Padding(0, 1),
+		Match:    lipgloss.NewStyle().Underline(true).Foreground(lipgloss.Color("214")),
+		Filter:   lipgloss.NewStyle().Foreground(lipgloss.Color("69")).Padding(0, 1),
+		Marked:   lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("214")),
 	}
 }
 
@@ -136,6 +267,7 @@ func (m *Model) Styles() Styles {
 // SetStyles sets the table styles.
 func (m *Model) SetStyles(s Styles) {
 	m.styles = s
+	m.invalidate()
 	m.UpdateViewport()
 }
 
@@ -147,17 +279,23 @@ type Option func(*Model)
 // New creates a new model for the table widget.
 func New(opts ...Option) Model {
 	m := Model{
-		cursor:   0,
-		viewport: viewport.New(0, 20), //nolint:mnd
+		cursor:     0,
+		viewHeight: 20, //nolint:mnd
+		filter:     textinput.New(),
+		sortCol:    -1,
 
 		KeyMap: DefaultKeyMap(),
 		Help:   help.New(),
 		styles: DefaultStyles(),
 	}
+	m.filter.Prompt = "/"
 
 	for _, opt := range opts {
 		opt(&m)
 	}
+	m.sortRows()
+	m.applyFilter()
+	m.layout()
 
 	m.UpdateViewport()
 
@@ -174,21 +312,21 @@ func WithColumns(cols []Column) Option {
 // WithRows sets the table rows (data).
 func WithRows(rows []Row) Option {
 	return func(m *Model) {
-		m.rows = rows
+		m.all = rows
 	}
 }
 
 // WithHeight sets the height of the table.
 func WithHeight(h int) Option {
 	return func(m *Model) {
-		m.viewport.Height = h - lipgloss.Height(m.headersView())
+		m.height = h
 	}
 }
 
 // WithWidth sets the width of the table.
 func WithWidth(w int) Option {
 	return func(m *Model) {
-		m.viewport.Width = w
+		m.viewWidth = w
 	}
 }
 
@@ -221,19 +359,36 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.filtering {
+			return m.updateFilter(msg)
+		}
+
 		switch {
+		case key.Matches(msg, m.KeyMap.Filter):
+			m.filtering = true
+			m.UpdateViewport()
+			return m, m.filter.Focus()
+		case key.Matches(msg, m.KeyMap.FilterCancel) && m.Filtered():
+			m.ClearFilter()
+		case key.Matches(msg, m.KeyMap.Mark):
+			m.ToggleMark()
+			m.MoveDown(1)
+		case key.Matches(msg, m.KeyMap.MarkAll):
+			m.MarkAll()
+		case key.Matches(msg, m.KeyMap.MarkInvert):
+			m.InvertMarks()
 		case key.Matches(msg, m.KeyMap.LineUp):
 			m.MoveUp(1)
 		case key.Matches(msg, m.KeyMap.LineDown):
 			m.MoveDown(1)
 		case key.Matches(msg, m.KeyMap.PageUp):
-			m.MoveUp(m.viewport.Height)
+			m.MoveUp(m.pageSize())
 		case key.Matches(msg, m.KeyMap.PageDown):
-			m.MoveDown(m.viewport.Height)
+			m.MoveDown(m.pageSize())
 		case key.Matches(msg, m.KeyMap.HalfPageUp):
-			m.MoveUp(m.viewport.Height / 2) //nolint:mnd
+			m.MoveUp(m.pageSize() / 2) //nolint:mnd
 		case key.Matches(msg, m.KeyMap.HalfPageDown):
-			m.MoveDown(m.viewport.Height / 2) //nolint:mnd
+			m.MoveDown(m.pageSize() / 2) //nolint:mnd
 		case key.Matches(msg, m.KeyMap.GotoTop):
 			m.GotoTop()
 		case key.Matches(msg, m.KeyMap.GotoBottom):
@@ -243,18 +398,318 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		case key.Matches(msg, m.KeyMap.ScrollRight):
 			m.MoveRight(20)
 		}
+	case tea.MouseMsg:
+		m.updateMouse(msg)
 	}
 
 	return m, nil
 }
 
+// updateFilter feeds a key to the filter input, narrowing the rows as the
+// query is typed.
+func (m Model) updateFilter(msg tea.KeyMsg) (Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch {
+	case key.Matches(msg, m.KeyMap.FilterAccept):
+		m.filtering = false
+		m.filter.Blur()
+		m.UpdateViewport()
+	case key.Matches(msg, m.KeyMap.FilterCancel):
+		m.ClearFilter()
+	case key.Matches(msg, m.KeyMap.FilterMode):
+		m.SetFilterMode((m.mode + 1) % filterModes)
+	default:
+		query := m.filter.Value()
+		m.filter, cmd = m.filter.Update(msg)
+		if m.filter.Value() != query {
+			m.reselect(m.applyFilter)
+		}
+	}
+	return m, cmd
+}
+
+// Filtering reports whether the filter input has the keyboard.
+func (m Model) Filtering() bool {
+	return m.filtering
+}
+
+// Filtered reports whether a filter query is set.
+func (m Model) Filtered() bool {
+	return m.filter.Value() != ""
+}
+
+// FilterValue returns the filter query.
+func (m Model) FilterValue() string {
+	return m.filter.Value()
+}
+
+// SetFilter sets the filter query.
+func (m *Model) SetFilter(query string) {
+	m.filter.SetValue(query)
+	m.reselect(m.applyFilter)
+}
+
+// SetFilterMode sets how the query is matched.
+func (m *Model) SetFilterMode(mode FilterMode) {
+	m.mode = mode
+	m.reselect(m.applyFilter)
+}
+
+// ClearFilter drops the filter query and shows every row again.
+func (m *Model) ClearFilter() {
+	m.filtering = false
+	m.filter.Blur()
+	m.filter.SetValue("")
+	m.reselect(m.applyFilter)
+}
+
+// applyFilter works out which rows pass the filter and where each of their
+// cells matched.
+func (m *Model) applyFilter() {
+	m.invalidate()
+	m.filterErr = nil
+	m.matches = nil
+	m.visible = make([]int, 0, len(m.all))
+
+	match, err := newMatcher(m.mode, m.filter.Value())
+	if err != nil {
+		m.filterErr = err
+	}
+	if match == nil {
+		m.rows = m.all
+		for i := range m.all {
+			m.visible = append(m.visible, i)
+		}
+		return
+	}
+
+	m.rows = make([]Row, 0, len(m.all))
+	for i, row := range m.all {
+		cells := make([][]int, len(row))
+		found := false
+		for j, value := range row {
+			cells[j] = m.matchCell(match, j, value)
+			found = found || cells[j] != nil
+		}
+		if found {
+			m.rows = append(m.rows, row)
+			m.visible = append(m.visible, i)
+			m.matches = append(m.matches, cells)
+		}
+	}
+}
+
+// matchCell matches the raw value of a cell, then the one shown. Only the
+// latter has positions to highlight.
+func (m *Model) matchCell(match matcher, col int, value string) []int {
+	if col >= len(m.cols) || m.cols[col].Format == nil {
+		return match(value)
+	}
+	if positions := match(m.cols[col].Format(value)); positions != nil {
+		return positions
+	}
+	if match(value) != nil {
+		return []int{}
+	}
+	return nil
+}
+
+// matcher returns the rune positions of value that match, an empty slice
+// for a match with nothing to highlight and nil for no match.
+type matcher func(value string) []int
+
+// newMatcher returns nil when there is nothing to filter on.
+func newMatcher(mode FilterMode, query string) (matcher, error) {
+	if query == "" {
+		return nil, nil
+	}
+
+	switch mode {
+	case FilterRegex:
+		if _, err := regexp.Compile(query); err != nil {
+			return nil, err
+		}
+		re := regexp.MustCompile("(?i)" + query)
+		return func(value string) []int {
+			loc := re.FindStringIndex(value)
+			if loc == nil {
+				return nil
+			}
+			return runeRange(value, loc[0], loc[1])
+		}, nil
+
+	case FilterFuzzy:
+		needle := []rune(strings.ToLower(query))
+		return func(value string) []int {
+			positions := []int{}
+			n := 0
+			for i, r := range []rune(strings.ToLower(value)) {
+				if n < len(needle) && r == needle[n] {
+					positions = append(positions, i)
+					n++
+				}
+			}
+			if n < len(needle) {
+				return nil
+			}
+			return positions
+		}, nil
+	}
+
+	needle := strings.ToLower(query)
+	return func(value string) []int {
+		lower := strings.ToLower(value)
+		idx := strings.Index(lower, needle)
+		if idx < 0 {
+			return nil
+		}
+		return runeRange(lower, idx, idx+len(needle))
+	}, nil
+}
+
+// runeRange turns the byte range start:end of s into rune positions.
+func runeRange(s string, start int, end int) []int {
+	first := utf8.RuneCountInString(s[:start])
+	count := utf8.RuneCountInString(s[start:end])
+	retval := make([]int, 0, count)
+	for i := 0; i < count; i++ {
+		retval = append(retval, first+i)
+	}
+	return retval
+}
+
+// ToggleMark marks the selected row, or unmarks it.
+func (m *Model) ToggleMark() {
+	row := m.SelectedRow()
+	if len(row) == 0 {
+		return
+	}
+	if m.marks == nil {
+		m.marks = make(map[string]bool)
+	}
+	if m.marks[row[0]] {
+		delete(m.marks, row[0])
+	} else {
+		m.marks[row[0]] = true
+	}
+	m.invalidate()
+	m.UpdateViewport()
+}
+
+// MarkAll marks every row passing the filter.
+func (m *Model) MarkAll() {
+	if m.marks == nil {
+		m.marks = make(map[string]bool)
+	}
+	for _, row := range m.rows {
+		if len(row) > 0 {
+			m.marks[row[0]] = true
+		}
+	}
+	m.invalidate()
+	m.UpdateViewport()
+}
+
+// InvertMarks flips the marks of the rows passing the filter.
+func (m *Model) InvertMarks() {
+	if m.marks == nil {
+		m.marks = make(map[string]bool)
+	}
+	for _, row := range m.rows {
+		if len(row) == 0 {
+			continue
+		}
+		if m.marks[row[0]] {
+			delete(m.marks, row[0])
+		} else {
+			m.marks[row[0]] = true
+		}
+	}
+	m.invalidate()
+	m.UpdateViewport()
+}
+
+// ClearMarks unmarks every row.
+func (m *Model) ClearMarks() {
+	m.marks = nil
+	m.invalidate()
+	m.UpdateViewport()
+}
+
+// Marked returns the first column of the marked rows, in row order, hidden
+// ones included.
+func (m Model) Marked() []string {
+	var retval []string
+	for _, row := range m.all {
+		if len(row) > 0 && m.marks[row[0]] {
+			retval = append(retval, row[0])
+		}
+	}
+	return retval
+}
+
+// IsMarked reports whether the row is marked.
+func (m Model) IsMarked(row Row) bool {
+	return len(row) > 0 && m.marks[row[0]]
+}
+
+// pruneMarks forgets the marks of rows that are gone.
+func (m *Model) pruneMarks() {
+	if len(m.marks) == 0 {
+		return
+	}
+	present := make(map[string]bool, len(m.all))
+	for _, row := range m.all {
+		if len(row) > 0 {
+			present[row[0]] = true
+		}
+	}
+	for id := range m.marks {
+		if !present[id] {
+			delete(m.marks, id)
+		}
+	}
+}
+
+// reselect runs change and puts the cursor back on the row it was on when
+// that row is still there.
+func (m *Model) reselect(change func()) {
+	selected := m.SelectedRow()
+	change()
+
+	if selected != nil {
+		for i, row := range m.rows {
+			if slices.Equal(row, selected) {
+				m.cursor = i
+				m.UpdateViewport()
+				return
+			}
+		}
+		for i, row := range m.rows {
+			if len(row) > 0 && row[0] == selected[0] {
+				m.cursor = i
+				m.UpdateViewport()
+				return
+			}
+		}
+	}
+
+	if m.cursor > len(m.rows)-1 {
+		m.cursor = len(m.rows) - 1
+	}
+	if m.cursor < 0 && len(m.rows) > 0 {
+		m.cursor = 0
+	}
+	m.UpdateViewport()
+}
+
 func (m *Model) MoveLeft(cols int) {
-	m.viewport.ScrollLeft(cols)
+	m.scrollX = clamp(m.scrollX-cols, 0, m.maxScrollX())
 	m.UpdateViewport()
 }
 
 func (m *Model) MoveRight(cols int) {
-	m.viewport.ScrollRight(cols)
+	m.scrollX = clamp(m.scrollX+cols, 0, m.maxScrollX())
 	m.UpdateViewport()
 }
 
@@ -278,7 +733,31 @@ func (m *Model) Blur() {
 
 // View renders the component.
 func (m Model) View() string {
-	return m.viewport.View()
+	filter := m.filterView()
+	if filter == "" {
+		return m.content
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, filter, m.content)
+}
+
+// filterView is the filter bar shown above the headers while a filter is
+// being typed or applied.
+func (m Model) filterView() string {
+	if !m.filtering && !m.Filtered() {
+		return ""
+	}
+
+	query := m.filter.Prompt + m.filter.Value()
+	if m.filtering {
+		query = m.filter.View()
+	}
+
+	status := fmt.Sprintf("%d of %d  [%s]", len(m.rows), len(m.all), m.mode)
+	if m.filterErr != nil {
+		status = fmt.Sprintf("invalid %s: %v", m.mode, m.filterErr)
+	}
+
+	return m.styles.Filter.Render(query + "  " + status)
 }
 
 // HelpView is a helper method for rendering the help menu from the keymap.
@@ -288,29 +767,89 @@ func (m Model) HelpView() string {
 	return m.Help.View(m.KeyMap)
 }
 
-// UpdateViewport updates the list content based on the previously defined
-// columns and rows.
+// UpdateViewport lays out the rows in view, keeping the cursor among them.
+// Only those rows are rendered, and each only once until the data or the
+// styles change, so the cost of a move does not grow with the table.
 func (m *Model) UpdateViewport() {
-	renderedRows := make([]string, 0, len(m.rows))
-	renderedRows = append(renderedRows, m.headersView())
-
-	// Render only rows from: m.cursor-m.viewport.Height to: m.cursor+m.viewport.Height
-	// Constant runtime, independent of number of rows in a table.
-	// Limits the number of renderedRows to a maximum of 2*m.viewport.Height
-	if m.cursor >= 0 {
-		m.start = clamp(m.cursor-m.viewport.Height+1, 0, m.cursor)
-	} else {
-		m.start = 0
+	header := m.headerLine()
+	if m.height > 0 {
+		m.viewHeight = m.height
+		if m.filtering || m.Filtered() {
+			m.viewHeight--
+		}
 	}
-	m.end = clamp(m.cursor+m.viewport.Height+1, m.cursor, len(m.rows))
+	m.scrollIntoView()
 
+	lines := make([]string, 0, m.viewHeight)
+	lines = append(lines, m.clip(header))
 	for i := m.start; i < m.end; i++ {
-		renderedRows = append(renderedRows, m.renderRow(i))
+		lines = append(lines, m.clip(m.renderedRow(i)))
 	}
+	for len(lines) < m.viewHeight {
+		lines = append(lines, m.clip(""))
+	}
+	m.content = strings.Join(lines, "\n")
+}
 
-	m.viewport.SetContent(
-		lipgloss.JoinVertical(lipgloss.Left, renderedRows...),
-	)
+// clip cuts line to the part scrolled into view and pads it to the width.
+func (m *Model) clip(line string) string {
+	if m.viewWidth <= 0 {
+		return line
+	}
+	if m.scrollX > 0 || ansi.StringWidth(line) > m.viewWidth {
+		line = ansi.Cut(line, m.scrollX, m.scrollX+m.viewWidth)
+	}
+	return line + strings.Repeat(" ", max(m.viewWidth-ansi.StringWidth(line), 0))
+}
+
+// pageSize is how many rows fit under the headers.
+func (m *Model) pageSize() int {
+	return max(m.viewHeight-lipgloss.Height(m.headerLine()), 1)
+}
+
+// scrollIntoView moves the window of rows shown as little as it takes for
+// the cursor to be in it, never leaving room below the last row.
+func (m *Model) scrollIntoView() {
+	page := m.pageSize()
+	if m.cursor < m.start {
+		m.start = m.cursor
+	}
+	if m.cursor >= m.start+page {
+		m.start = m.cursor - page + 1
+	}
+	m.start = clamp(m.start, 0, max(len(m.rows)-page, 0))
+	m.end = min(m.start+page, len(m.rows))
+}
+
+// invalidate drops the rendered rows. A new map is made rather than the
+// old one cleared as copies of the model share it.
+func (m *Model) invalidate() {
+	m.rendered = nil
+	m.header = ""
+}
+
+// headerLine is the rendered headers, cached like the rows.
+func (m *Model) headerLine() string {
+	if m.header == "" {
+		m.header = m.headersView()
+	}
+	return m.header
+}
+
+// renderedRow is row r as drawn, the cursor row highlighted.
+func (m *Model) renderedRow(r int) string {
+	row, ok := m.rendered[r]
+	if !ok {
+		if m.rendered == nil {
+			m.rendered = make(map[int]string)
+		}
+		row = m.renderRow(r)
+		m.rendered[r] = row
+	}
+	if r == m.cursor {
+		return m.styles.Selected.Render(row)
+	}
+	return row
 }
 
 // SelectedRow returns the selected row.
@@ -323,64 +862,79 @@ func (m Model) SelectedRow() Row {
 	return m.rows[m.cursor]
 }
 
-// Rows returns the current rows.
+// Rows returns the rows passing the filter, the ones the cursor moves over.
 func (m Model) Rows() []Row {
 	return m.rows
 }
 
+// AllRows returns every row, filtered or not.
+func (m Model) AllRows() []Row {
+	return m.all
+}
+
+// SelectedIndex returns the position of the selected row in AllRows.
+func (m Model) SelectedIndex() int {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return -1
+	}
+	return m.visible[m.cursor]
+}
+
 // Columns returns the current columns.
 func (m Model) Columns() []Column {
 	return m.cols
 }
 
-// SetRows sets a new rows state.
+// SetRows sets a new rows state. The filter is applied to them and the
+// cursor stays on the same row when it is still there.
 func (m *Model) SetRows(r []Row) {
-	m.rows = r
-
-	if m.cursor > len(m.rows)-1 {
-		m.cursor = len(m.rows) - 1
-	}
-
-	m.UpdateViewport()
+	m.reselect(func() {
+		m.all = r
+		m.pruneMarks()
+		m.sortRows()
+		m.applyFilter()
+	})
 }
 
-// SetColumns sets a new columns state.
+// SetColumns sets a new columns state, showing all of them.
 func (m *Model) SetColumns(c []Column) {
 	m.cols = c
+	m.order = nil
+	m.layout()
 	m.UpdateViewport()
 }
 
+// SetData sets new columns, showing all of them, and rows.
 func (m *Model) SetData(c []Column, r []Row) {
 	m.cols = c
-	m.rows = r
-
-	if m.cursor > len(m.rows)-1 {
-		m.cursor = len(m.rows) - 1
-	}
-
-	m.UpdateViewport()
+	m.order = nil
+	m.layout()
+	m.SetRows(r)
 }
 
-// SetWidth sets the width of the viewport of the table.
+// SetWidth sets the width of the table, laying the columns
+// out again to fit.
 func (m *Model) SetWidth(w int) {
-	m.viewport.Width = w
+	m.viewWidth = w
+	m.layout()
 	m.UpdateViewport()
 }
 
-// SetHeight sets the height of the viewport of the table.
+// SetHeight sets the height of the table, the filter bar and headers
+// included.
 func (m *Model) SetHeight(h int) {
-	m.viewport.Height = h - lipgloss.Height(m.headersView())
+	m.height = h
 	m.UpdateViewport()
 }
 
-// Height returns the viewport height of the table.
+// Height returns the height of the headers and rows of the table.
 func (m Model) Height() int {
-	return m.viewport.Height
+	return m.viewHeight
 }
 
-// Width returns the viewport width of the table.
+// Width returns the width of the table.
 func (m Model) Width() int {
-	return m.viewport.Width
+	return m.viewWidth
 }
 
 // Cursor returns the index of the selected row.
@@ -398,14 +952,6 @@ func (m *Model) SetCursor(n int) {
 // It can not go above the first row.
 func (m *Model) MoveUp(n int) {
 	m.cursor = clamp(m.cursor-n, 0, len(m.rows)-1)
-	switch {
-	case m.start == 0:
-		m.viewport.SetYOffset(clamp(m.viewport.YOffset, 0, m.cursor))
-	case m.start < m.viewport.Height:
-		m.viewport.YOffset = (clamp(clamp(m.viewport.YOffset+n, 0, m.cursor), 0, m.viewport.Height))
-	case m.viewport.YOffset >= 1:
-		m.viewport.YOffset = clamp(m.viewport.YOffset+n, 1, m.viewport.Height)
-	}
 	m.UpdateViewport()
 }
 
@@ -414,16 +960,6 @@ func (m *Model) MoveUp(n int) {
 func (m *Model) MoveDown(n int) {
 	m.cursor = clamp(m.cursor+n, 0, len(m.rows)-1)
 	m.UpdateViewport()
-
-	switch {
-	case m.end == len(m.rows) && m.viewport.YOffset > 0:
-		m.viewport.SetYOffset(clamp(m.viewport.YOffset-n, 1, m.viewport.Height))
-	case m.cursor > (m.end-m.start)/2 && m.viewport.YOffset > 0:
-		m.viewport.SetYOffset(clamp(m.viewport.YOffset-n, 1, m.cursor))
-	case m.viewport.YOffset > 1:
-	case m.cursor > m.viewport.YOffset+m.viewport.Height-1:
-		m.viewport.SetYOffset(clamp(m.viewport.YOffset+1, 0, 1))
-	}
 }
 
 // GotoTop moves the selection to the first row.
@@ -454,39 +990,162 @@ func (m *Model) FromValues(value, separator string) {
 
 func (m Model) headersView() string {
 	s := make([]string, 0, len(m.cols))
-	for _, col := range m.cols {
-		if col.Width <= 0 {
+	for _, i := range m.shown() {
+		col := m.cols[i]
+		width := m.width(i)
+		if width <= 0 {
 			continue
 		}
-		style := lipgloss.NewStyle().Width(col.Width).MaxWidth(col.Width).Inline(true)
-		renderedCell := style.Render(runewidth.Truncate(col.Title, col.Width, "…"))
+		title := runewidth.Truncate(col.Title, width, "…")
+		if i == m.sortCol {
+			arrow := "▲"
+			if m.sortDesc {
+				arrow = "▼"
+			}
+			// the arrow stays visible however narrow the column
+			title = runewidth.Truncate(col.Title, max(width-2, 0), "…")
+			title = strings.TrimSpace(title + " " + arrow)
+		}
+		style := lipgloss.NewStyle().Width(width).MaxWidth(width).Inline(true)
+		renderedCell := style.Render(title)
 		s = append(s, m.styles.Header.Render(renderedCell))
 	}
 	return lipgloss.JoinHorizontal(lipgloss.Top, s...)
 }
 
+// SetOrder sets which columns are shown and in what order, by index, the
+// rows keeping theirs. nil shows every column in place.
+func (m *Model) SetOrder(order []int) {
+	m.order = slices.DeleteFunc(slices.Clone(order), func(i int) bool {
+		return i < 0 || i >= len(m.cols)
+	})
+	if order == nil {
+		m.order = nil
+	}
+	m.layout()
+	m.UpdateViewport()
+}
+
+// Order returns the indexes of the columns shown, in the order shown.
+func (m Model) Order() []int {
+	return m.shown()
+}
+
+func (m Model) shown() []int {
+	if m.order != nil {
+		return m.order
+	}
+	retval := make([]int, len(m.cols))
+	for i := range m.cols {
+		retval[i] = i
+	}
+	return retval
+}
+
+// width is the width column i is drawn at.
+func (m *Model) width(i int) int {
+	if i < len(m.widths) {
+		return m.widths[i]
+	}
+	if i < len(m.cols) {
+		return m.cols[i].Width
+	}
+	return 0
+}
+
 func (m *Model) renderRow(r int) string {
 	s := make([]string, 0, len(m.cols))
-	for i, value := range m.rows[r] {
-		if m.cols[i].Width <= 0 {
+	marked := m.IsMarked(m.rows[r])
+	for _, i := range m.shown() {
+		if i >= len(m.rows[r]) || m.width(i) <= 0 {
 			continue
 		}
-		style := lipgloss.NewStyle().Width(m.cols[i].Width).MaxWidth(m.cols[i].Width).Inline(true)
-		rowStyle := lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("225"))
+		value := m.rows[r][i]
+		rowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("225"))
 		if m.styles.Cell != nil {
-			rowStyle = m.styles.Cell(m.rows[r]).Padding(0, 1)
+			rowStyle = m.styles.Cell(m.rows[r])
+		}
+		if marked {
+			rowStyle = m.styles.Marked.Inherit(rowStyle)
+		}
+
+		var matched []int
+		if m.matches != nil {
+			matched = m.matches[r][i]
 		}
-		renderedCell := rowStyle.Render(style.Render(runewidth.Truncate(value, m.cols[i].Width, "…")))
-		s = append(s, renderedCell)
+		display := m.cols[i].Display(value)
+		var spans []Span
+		if m.cols[i].Highlight != nil {
+			spans = m.cols[i].Highlight(display)
+		}
+		cell := m.renderCell(display, m.width(i), rowStyle, matched, spans)
+		if marked && len(s) == 0 {
+			// the left padding of the first cell carries the mark
+			cell = rowStyle.Render("•") + strings.TrimPrefix(cell, " ")
+		}
+		s = append(s, cell)
 	}
 
-	row := lipgloss.JoinHorizontal(lipgloss.Top, s...)
+	return lipgloss.JoinHorizontal(lipgloss.Top, s...)
+}
 
-	if r == m.cursor {
-		return m.styles.Selected.Render(row)
+// renderCell truncates value to width and styles it, highlighting the
+// runes at the matched positions and those of the spans.
+func (m *Model) renderCell(value string, width int, base lipgloss.Style, matched []int, spans []Span) string {
+	text := runewidth.Truncate(value, width, "…")
+
+	// runes go by style: a span index, or one of these
+	const (
+		plain = -1
+		match = -2
+	)
+	style := func(class int) lipgloss.Style {
+		switch class {
+		case plain:
+			return base
+		case match:
+			return m.styles.Match.Inherit(base)
+		}
+		return spans[class].Style.Inherit(base)
 	}
 
-	return row
+	var b strings.Builder
+	var segment []rune
+	current := plain
+	flush := func() {
+		if len(segment) == 0 {
+			return
+		}
+		b.WriteString(style(current).Render(string(segment)))
+		segment = segment[:0]
+	}
+
+	next := 0
+	for i, r := range []rune(text) {
+		for next < len(matched) && matched[next] < i {
+			next++
+		}
+		class := plain
+		if next < len(matched) && matched[next] == i {
+			class = match
+		} else {
+			for j, span := range spans {
+				if i >= span.Start && i < span.End {
+					class = j
+					break
+				}
+			}
+		}
+		if class != current {
+			flush()
+			current = class
+		}
+		segment = append(segment, r)
+	}
+	flush()
+
+	pad := max(width-runewidth.StringWidth(text), 0)
+	return " " + b.String() + strings.Repeat(" ", pad) + " "
 }
 
 func clamp(v, low, high int) int {
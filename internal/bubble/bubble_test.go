@@ -0,0 +1,184 @@
+package bubble
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var benchSizes = []int{10000, 50000}
+
+func testColumns() []Column {
+	return []Column{
+		{Title: "ID", Width: 12},
+		{Title: "Name", Width: 20, Flex: 1},
+		{Title: "Size", Width: 8, Type: ColumnBytes},
+	}
+}
+
+func testRows(n int) []Row {
+	rows := make([]Row, n)
+	for i := range rows {
+		rows[i] = Row{fmt.Sprintf("%012x", i), fmt.Sprintf("container-%d", i), fmt.Sprint(i * 1024)}
+	}
+	return rows
+}
+
+func testModel(n int, height int) Model {
+	return New(
+		WithColumns(testColumns()),
+		WithRows(testRows(n)),
+		WithHeight(height),
+		WithWidth(80),
+		WithFocused(true),
+	)
+}
+
+// checkWindow makes sure the cursor is among the rows drawn, and that as
+// many rows are drawn as fit.
+func checkWindow(t *testing.T, m Model) {
+	t.Helper()
+	page := m.pageSize()
+	if len(m.rows) == 0 {
+		if m.start != 0 || m.end != 0 {
+			t.Errorf("window %d-%d over no rows", m.start, m.end)
+		}
+		return
+	}
+	if m.cursor < m.start || m.cursor >= m.end {
+		t.Errorf("cursor %d outside the window %d-%d", m.cursor, m.start, m.end)
+	}
+	if want := min(page, len(m.rows)); m.end-m.start != want {
+		t.Errorf("window %d-%d shows %d rows, want %d", m.start, m.end, m.end-m.start, want)
+	}
+	if lines := strings.Count(m.content, "\n") + 1; lines > max(m.viewHeight, lipgloss.Height(m.headerLine())+page) {
+		t.Errorf("view is %d lines high for a height of %d", lines, m.viewHeight)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   int
+		height int
+		move   func(m *Model)
+		cursor int
+		start  int
+	}{
+		{"first row", 100, 11, func(m *Model) { m.GotoTop() }, 0, 0},
+		{"last row", 100, 11, func(m *Model) { m.GotoBottom() }, 99, 90},
+		{"past the last row", 100, 11, func(m *Model) { m.MoveDown(1000) }, 99, 90},
+		{"before the first row", 100, 11, func(m *Model) { m.MoveDown(50); m.MoveUp(1000) }, 0, 0},
+		{"down a page", 100, 11, func(m *Model) { m.MoveDown(m.pageSize()) }, 10, 1},
+		{"back up", 100, 11, func(m *Model) { m.GotoBottom(); m.MoveUp(10) }, 89, 89},
+		{"fewer rows than fit", 3, 11, func(m *Model) { m.GotoBottom() }, 2, 0},
+		{"no rows", 0, 11, func(m *Model) { m.GotoBottom() }, -1, 0},
+		{"one row high", 100, 2, func(m *Model) { m.MoveDown(5) }, 5, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testModel(tt.rows, tt.height)
+			tt.move(&m)
+			if m.cursor != tt.cursor || m.start != tt.start {
+				t.Errorf("cursor %d from %d, want %d from %d", m.cursor, m.start, tt.cursor, tt.start)
+			}
+			checkWindow(t, m)
+		})
+	}
+}
+
+func TestWindowShorterThanHeader(t *testing.T) {
+	m := testModel(100, 20)
+	styles := m.Styles()
+	styles.Header = styles.Header.Border(lipgloss.NormalBorder())
+	m.SetStyles(styles)
+
+	for _, height := range []int{3, 2, 1, 0} {
+		m.SetHeight(height)
+		m.MoveDown(7)
+		// always room for the cursor row, however little there is
+		if m.pageSize() != 1 {
+			t.Errorf("height %d: page of %d rows, want 1", height, m.pageSize())
+		}
+		checkWindow(t, m)
+	}
+}
+
+func TestWindowFilter(t *testing.T) {
+	m := testModel(1000, 11)
+	m.SetCursor(900)
+	if m.start != 891 {
+		t.Fatalf("window starts at %d, want 891", m.start)
+	}
+
+	// fewer rows pass than the window started at, and the filter bar
+	// takes a line off the page
+	m.SetFilter("container-99")
+	if len(m.rows) != 11 {
+		t.Fatalf("%d rows pass the filter, want 11", len(m.rows))
+	}
+	if m.start != 2 || m.cursor != 10 {
+		t.Errorf("cursor %d from %d, want the last row from 2", m.cursor, m.start)
+	}
+	checkWindow(t, m)
+
+	m.SetFilter("nothing matches this")
+	if m.cursor != -1 {
+		t.Errorf("cursor %d with no rows", m.cursor)
+	}
+	checkWindow(t, m)
+
+	// the cursor comes back to the row it was last on
+	m.SetFilter("container-995")
+	m.ClearFilter()
+	if m.cursor != 995 {
+		t.Errorf("cursor %d after clearing the filter, want 995", m.cursor)
+	}
+	checkWindow(t, m)
+}
+
+func BenchmarkMoveDown(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			m := testModel(n, 40)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if m.cursor == n-1 {
+					m.GotoTop()
+				}
+				m.MoveDown(1)
+			}
+		})
+	}
+}
+
+func BenchmarkPageDown(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			m := testModel(n, 40)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if m.cursor == n-1 {
+					m.GotoTop()
+				}
+				m.MoveDown(m.pageSize())
+			}
+		})
+	}
+}
+
+func BenchmarkSetRows(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			m := testModel(n, 40)
+			rows := testRows(n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.SetRows(rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package bubble
+
+import (
+	"github.com/mattn/go-runewidth"
+)
+
+// space the padding of a cell takes on top of its width
+const cellPadding = 2
+
+// smallest a flexible column shrinks to without a MinWidth of its own
+const defaultMinWidth = 4
+
+// layout works out the width every column is drawn at so the table fits
+// the width: flexible columns shrink first, then columns are hidden by
+// priority. A table that still does not fit is left to horizontal scrolling.
+func (m *Model) layout() {
+	m.invalidate()
+	m.widths = make([]int, len(m.cols))
+	available := m.viewWidth
+	for _, i := range m.shown() {
+		m.widths[i] = naturalWidth(m.cols[i])
+	}
+	if available <= 0 {
+		return
+	}
+
+	for {
+		if m.shrink(available) {
+			return
+		}
+
+		// drop the shown column with the highest hide priority
+		victim := -1
+		for i, col := range m.cols {
+			if m.widths[i] > 0 && col.Hide > 0 && (victim < 0 || col.Hide > m.cols[victim].Hide) {
+				victim = i
+			}
+		}
+		if victim < 0 {
+			return
+		}
+		m.widths[victim] = 0
+
+		// hiding frees room, start over from the natural widths
+		for i, col := range m.cols {
+			if m.widths[i] > 0 {
+				m.widths[i] = naturalWidth(col)
+			}
+		}
+	}
+}
+
+// shrink takes the overflow out of the flexible columns in proportion to
+// their flex and to the room they have above their minimum, so the widest
+// give up the most. It reports whether the shown columns fit.
+func (m *Model) shrink(available int) bool {
+	for {
+		overflow := m.totalWidth() - available
+		if overflow <= 0 {
+			return true
+		}
+
+		weight := 0
+		for i, col := range m.cols {
+			if col.Flex > 0 && m.widths[i] > minWidth(col) {
+				weight += col.Flex * (m.widths[i] - minWidth(col))
+			}
+		}
+		if weight == 0 {
+			return false
+		}
+
+		for i, col := range m.cols {
+			slack := m.widths[i] - minWidth(col)
+			if col.Flex <= 0 || slack <= 0 {
+				continue
+			}
+			cut := min(max(overflow*col.Flex*slack/weight, 1), slack)
+			m.widths[i] -= cut
+			overflow -= cut
+			if overflow <= 0 {
+				break
+			}
+		}
+	}
+}
+
+func (m *Model) totalWidth() int {
+	total := 0
+	for _, w := range m.widths {
+		if w > 0 {
+			total += w + cellPadding
+		}
+	}
+	return total
+}
+
+// naturalWidth is what the column takes given all the room it wants: its
+// widest value or its title, within its bounds.
+func naturalWidth(col Column) int {
+	if col.Width <= 0 {
+		return 0
+	}
+	w := max(col.Width, runewidth.StringWidth(col.Title), col.MinWidth)
+	if col.MaxWidth > 0 {
+		w = min(w, col.MaxWidth)
+	}
+	return w
+}
+
+func minWidth(col Column) int {
+	if col.MinWidth > 0 {
+		return col.MinWidth
+	}
+	return min(defaultMinWidth, naturalWidth(col))
+}
+
+// ColumnWidths returns the width each column is drawn at, 0 for those
+// hidden to make the table fit.
+func (m Model) ColumnWidths() []int {
+	return m.widths
+}
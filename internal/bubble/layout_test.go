@@ -0,0 +1,132 @@
+package bubble
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	containers := []Column{
+		{Title: "ID", Width: 10},
+		{Title: "Name", Width: 10, Flex: 1, MinWidth: 8},
+		{Title: "Ports", Width: 20, Hide: 1},
+		{Title: "Labels", Width: 20, Hide: 2},
+	}
+
+	tests := []struct {
+		name  string
+		cols  []Column
+		width int
+		want  []int
+	}{
+		{
+			name:  "natural widths",
+			cols:  []Column{{Title: "Name", Width: 3}, {Title: "Command", Width: 30, MaxWidth: 12}, {Title: "X", Width: 1, MinWidth: 5}},
+			width: 80,
+			want:  []int{4, 12, 5},
+		},
+		{
+			name:  "no width yet",
+			cols:  []Column{{Title: "Name", Width: 30, Flex: 1}},
+			width: 0,
+			want:  []int{30},
+		},
+		{
+			name:  "flexible column shrinks",
+			cols:  []Column{{Title: "A", Width: 10, Flex: 1}, {Title: "B", Width: 10}},
+			width: 20,
+			want:  []int{6, 10},
+		},
+		{
+			// both give up room in proportion to what they have above the
+			// default minimum
+			name:  "widest gives up most",
+			cols:  []Column{{Title: "A", Width: 20, Flex: 1}, {Title: "B", Width: 10, Flex: 1}},
+			width: 28,
+			want:  []int{15, 9},
+		},
+		{
+			name:  "flex weights the cut",
+			cols:  []Column{{Title: "A", Width: 20, Flex: 3}, {Title: "B", Width: 20, Flex: 1}},
+			width: 36,
+			want:  []int{13, 19},
+		},
+		{
+			// what is left is for horizontal scrolling
+			name:  "not below the minimum",
+			cols:  []Column{{Title: "A", Width: 20, Flex: 1, MinWidth: 15}, {Title: "B", Width: 10}},
+			width: 20,
+			want:  []int{15, 10},
+		},
+		{
+			name:  "never hidden",
+			cols:  []Column{{Title: "A", Width: 30}, {Title: "B", Width: 30}},
+			width: 20,
+			want:  []int{30, 30},
+		},
+		{
+			name:  "fits",
+			cols:  containers,
+			width: 68,
+			want:  []int{10, 10, 20, 20},
+		},
+		{
+			name:  "highest hide priority first",
+			cols:  containers,
+			width: 60,
+			want:  []int{10, 10, 20, 0},
+		},
+		{
+			// hiding starts over from the natural widths and shrinks again
+			name:  "shrinks after hiding",
+			cols:  containers,
+			width: 45,
+			want:  []int{10, 9, 20, 0},
+		},
+		{
+			name:  "hides until it fits",
+			cols:  containers,
+			width: 40,
+			want:  []int{10, 10, 0, 0},
+		},
+		{
+			name:  "minimum after hiding everything",
+			cols:  containers,
+			width: 15,
+			want:  []int{10, 8, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(WithColumns(tt.cols), WithWidth(tt.width))
+			if got := m.ColumnWidths(); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayoutOrder(t *testing.T) {
+	cols := []Column{
+		{Title: "ID", Width: 10},
+		{Title: "Name", Width: 10, Flex: 1},
+		{Title: "Labels", Width: 20, Hide: 1},
+	}
+	m := New(WithColumns(cols), WithWidth(34))
+	if got, want := m.ColumnWidths(), []int{10, 10, 0}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// a column left out of the order frees its room for the others
+	m.SetOrder([]int{2, 0})
+	if got, want := m.ColumnWidths(), []int{10, 0, 20}; !slices.Equal(got, want) {
+		t.Errorf("ordered: got %v, want %v", got, want)
+	}
+
+	m.SetOrder(nil)
+	m.SetWidth(80)
+	if got, want := m.ColumnWidths(), []int{10, 10, 20}; !slices.Equal(got, want) {
+		t.Errorf("widened: got %v, want %v", got, want)
+	}
+}
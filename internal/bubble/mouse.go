@@ -0,0 +1,95 @@
+package bubble
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// rows the wheel scrolls by a notch
+const wheelStep = 3
+
+// updateMouse scrolls on the wheel, selects the row clicked and sorts on
+// the header clicked. Coordinates are relative to the top left corner of
+// the table.
+func (m *Model) updateMouse(msg tea.MouseMsg) {
+	if m.filtering {
+		return
+	}
+
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		m.MoveUp(wheelStep)
+	case msg.Button == tea.MouseButtonWheelDown:
+		m.MoveDown(wheelStep)
+	case msg.Button == tea.MouseButtonWheelLeft:
+		m.MoveLeft(wheelStep)
+	case msg.Button == tea.MouseButtonWheelRight:
+		m.MoveRight(wheelStep)
+	case msg.Action != tea.MouseActionPress || msg.Button != tea.MouseButtonLeft:
+	case m.HeaderAt(msg.Y):
+		if pos := m.ColumnAt(msg.X); pos >= 0 {
+			m.SortBy(pos)
+		}
+	default:
+		if row := m.RowAt(msg.Y); row >= 0 {
+			m.SetCursor(row)
+		}
+	}
+}
+
+// line turns y into a line of the headers and rows, -1 when y is outside
+// them.
+func (m Model) line(y int) int {
+	if m.filtering || m.Filtered() {
+		y -= lipgloss.Height(m.filterView())
+	}
+	if y < 0 || y >= m.viewHeight {
+		return -1
+	}
+	return y
+}
+
+// HeaderAt reports whether y falls on the headers.
+func (m Model) HeaderAt(y int) bool {
+	line := m.line(y)
+	return line >= 0 && line < lipgloss.Height(m.headerLine())
+}
+
+// RowAt returns the index in Rows of the row shown at y, -1 when there is
+// none.
+func (m Model) RowAt(y int) int {
+	line := m.line(y)
+	if line < 0 {
+		return -1
+	}
+	row := m.start + line - lipgloss.Height(m.headerLine())
+	if row < m.start || row >= m.end {
+		return -1
+	}
+	return row
+}
+
+// ColumnAt returns the position, among those shown, of the column drawn at
+// x, -1 when there is none.
+func (m Model) ColumnAt(x int) int {
+	x += m.scrollX
+	if x < 0 {
+		return -1
+	}
+	left := 0
+	for pos, i := range m.shown() {
+		width := m.width(i)
+		if width <= 0 {
+			continue
+		}
+		left += width + cellPadding
+		if x < left {
+			return pos
+		}
+	}
+	return -1
+}
+
+func (m Model) maxScrollX() int {
+	return max(lipgloss.Width(m.headerLine())-m.viewWidth, 0)
+}
@@ -0,0 +1,207 @@
+package bubble
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/docker/go-units"
+)
+
+// ColumnType tells how the values of a column compare when sorting.
+type ColumnType int
+
+const (
+	ColumnText ColumnType = iota
+	ColumnInteger
+	ColumnBytes
+	ColumnTime
+	ColumnVersion
+)
+
+// layouts tried, in order, on the values of time columns
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.DateTime,
+	"2006-01-02 15:04:05 -0700 MST",
+	time.DateOnly,
+}
+
+// SortBy sorts on the column shown at position pos, flipping the direction
+// when it already is the sort column.
+func (m *Model) SortBy(pos int) {
+	shown := m.shown()
+	if pos < 0 || pos >= len(shown) {
+		return
+	}
+	col := shown[pos]
+	desc := false
+	if col == m.sortCol {
+		desc = !m.sortDesc
+	}
+	m.SetSort(col, desc)
+}
+
+// SetSort sorts on the column in the direction given, a negative column
+// keeps the rows in the order they were set.
+func (m *Model) SetSort(col int, desc bool) {
+	m.sortCol = col
+	m.sortDesc = desc
+	m.reselect(func() {
+		m.sortRows()
+		m.applyFilter()
+	})
+}
+
+// Sort returns the sort column and whether it is descending.
+func (m Model) Sort() (int, bool) {
+	return m.sortCol, m.sortDesc
+}
+
+// sortRows orders every row on the sort column, ties going by the first
+// column and then by the order the rows came in.
+func (m *Model) sortRows() {
+	col := m.sortCol
+	if col < 0 || col >= len(m.cols) {
+		return
+	}
+
+	kind := m.cols[col].Type
+	slices.SortStableFunc(m.all, func(a, b Row) int {
+		c := compareValues(kind, cell(a, col), cell(b, col))
+		if m.sortDesc {
+			c = -c
+		}
+		if c == 0 && col != 0 {
+			c = compareValues(m.cols[0].Type, cell(a, 0), cell(b, 0))
+		}
+		return c
+	})
+}
+
+func cell(row Row, col int) string {
+	if col < len(row) {
+		return row[col]
+	}
+	return ""
+}
+
+// compareValues compares two values of a column, those that do not parse
+// as the column type go after those that do.
+func compareValues(kind ColumnType, a string, b string) int {
+	switch kind {
+	case ColumnInteger:
+		return compareParsed(a, b, func(s string) (float64, bool) {
+			v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			return v, err == nil
+		})
+	case ColumnBytes:
+		return compareParsed(a, b, ParseBytes)
+	case ColumnTime:
+		return compareParsed(a, b, func(s string) (int64, bool) {
+			t, ok := ParseTime(s)
+			return t.Unix(), ok
+		})
+	case ColumnVersion:
+		return compareVersions(a, b)
+	}
+	return compareText(a, b)
+}
+
+func compareParsed[T cmp.Ordered](a string, b string, parse func(string) (T, bool)) int {
+	va, oka := parse(a)
+	vb, okb := parse(b)
+	switch {
+	case oka && okb:
+		return cmp.Compare(va, vb)
+	case oka:
+		return -1
+	case okb:
+		return 1
+	}
+	return compareText(a, b)
+}
+
+func compareText(a string, b string) int {
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// ParseBytes reads the raw value of a bytes column: plain byte counts as
+// well as sizes like 12.3MB or 1GiB.
+func ParseBytes(s string) (int64, bool) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v, true
+	}
+	if strings.Contains(s, "i") {
+		v, err := units.RAMInBytes(s)
+		return v, err == nil
+	}
+	v, err := units.FromHumanSize(s)
+	return v, err == nil
+}
+
+// ParseTime reads the raw value of a time column: unix seconds or one of
+// the layouts shown in the tables.
+func ParseTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(v, 0), true
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// compareVersions compares tags the way people read them: runs of digits
+// by value, the rest as text, so 1.10 comes after 1.9.
+func compareVersions(a string, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, erra := strconv.ParseUint(pa[i], 10, 64)
+		nb, errb := strconv.ParseUint(pb[i], 10, 64)
+		var c int
+		switch {
+		case erra == nil && errb == nil:
+			c = cmp.Compare(na, nb)
+		case erra == nil:
+			c = -1
+		case errb == nil:
+			c = 1
+		default:
+			c = compareText(pa[i], pb[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	if c := cmp.Compare(len(pa), len(pb)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// versionParts splits s into runs of digits and runs of anything else.
+func versionParts(s string) []string {
+	var retval []string
+	start := 0
+	for i, r := range s {
+		if i > start && unicode.IsDigit(r) != unicode.IsDigit(rune(s[start])) {
+			retval = append(retval, s[start:i])
+			start = i
+		}
+	}
+	if start < len(s) {
+		retval = append(retval, s[start:])
+	}
+	return retval
+}
@@ -0,0 +1,150 @@
+package bubble
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func sign(c int) int {
+	switch {
+	case c < 0:
+		return -1
+	case c > 0:
+		return 1
+	}
+	return 0
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		name string
+		kind ColumnType
+		a    string
+		b    string
+		want int
+	}{
+		{"text", ColumnText, "alpha", "beta", -1},
+		{"text ignores case", ColumnText, "Beta", "alpha", 1},
+		{"text case breaks ties", ColumnText, "Alpha", "alpha", -1},
+		{"integer", ColumnInteger, "9", "10", -1},
+		{"integer fraction", ColumnInteger, "1.5", "1.25", 1},
+		{"integer equal", ColumnInteger, " 7", "7", 0},
+		{"integer unparsable last", ColumnInteger, "n/a", "5", 1},
+		{"integer parsable first", ColumnInteger, "5", "", -1},
+		{"integer both unparsable", ColumnInteger, "b", "a", 1},
+		{"bytes", ColumnBytes, "900", "1024", -1},
+		{"bytes units", ColumnBytes, "1.5GB", "900MB", 1},
+		{"bytes binary units", ColumnBytes, "1GiB", "1000MB", 1},
+		{"bytes unparsable last", ColumnBytes, "-", "0", 1},
+		{"time", ColumnTime, "2024-01-02T03:04:05Z", "2024-01-02T03:04:06Z", -1},
+		{"time layouts mixed", ColumnTime, "2023-12-31", "1700000000", 1},
+		{"time unparsable last", ColumnTime, "never", "2024-01-02 03:04:05", 1},
+		{"version", ColumnVersion, "1.10", "1.9", 1},
+		{"version prefix", ColumnVersion, "v1.2", "v1.10", -1},
+		{"version longer", ColumnVersion, "1.2.1", "1.2", 1},
+		{"version numbers first", ColumnVersion, "latest", "1.0", 1},
+		{"version equal", ColumnVersion, "3.19", "3.19", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sign(compareValues(tt.kind, tt.a, tt.b)); got != tt.want {
+				t.Errorf("compareValues(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := sign(compareValues(tt.kind, tt.b, tt.a)); got != -tt.want {
+				t.Errorf("compareValues(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		ok    bool
+	}{
+		{"0", 0, true},
+		{" 1234 ", 1234, true},
+		{"12.3MB", 12300000, true},
+		{"1kB", 1000, true},
+		{"1GiB", 1 << 30, true},
+		{"512KiB", 512 << 10, true},
+		{"", 0, false},
+		{"-", 0, false},
+		{"lots", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseBytes(tt.value)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("ParseBytes(%q) = %d %v, want %d %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"1700000000", time.Unix(1700000000, 0), true},
+		{"2024-01-02T03:04:05.123Z", time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC), true},
+		{"2024-01-02 03:04:05", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), true},
+		{"2024-01-02 03:04:05 +0000 UTC", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), true},
+		{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), true},
+		{"3 days ago", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseTime(tt.value)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %v %v, want %v %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSetSort(t *testing.T) {
+	m := New(
+		WithColumns([]Column{{Title: "Name", Width: 4}, {Title: "Size", Width: 5, Type: ColumnBytes}}),
+		WithRows([]Row{{"b", "2MB"}, {"a", "?"}, {"c", "512"}, {"d", "2MB"}}),
+	)
+
+	names := func() []string {
+		var retval []string
+		for _, row := range m.Rows() {
+			retval = append(retval, row[0])
+		}
+		return retval
+	}
+
+	tests := []struct {
+		name string
+		col  int
+		desc bool
+		want []string
+	}{
+		// ties go by the first column, the unparsable size last
+		{"ascending", 1, false, []string{"c", "b", "d", "a"}},
+		{"descending", 1, true, []string{"a", "b", "d", "c"}},
+		{"first column", 0, true, []string{"d", "c", "b", "a"}},
+		{"unsorted", -1, false, []string{"d", "c", "b", "a"}},
+	}
+
+	for _, tt := range tests {
+		m.SetSort(tt.col, tt.desc)
+		if got := names(); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// sorting again on the same column flips it
+	m.SortBy(1)
+	m.SortBy(1)
+	if col, desc := m.Sort(); col != 1 || !desc {
+		t.Errorf("sort %d %v, want 1 descending", col, desc)
+	}
+}
@@ -0,0 +1,102 @@
+// Package config keeps the settings users tailor yadc with in a YAML file,
+// by default $XDG_CONFIG_HOME/yadc/config.yaml.
+package config
+
+import (
+	"errors"
+	"github.com/presselam/yadc/internal/logger"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+type Config struct {
+	// Columns lists, by mode, the columns shown in the order shown
+	Columns map[string][]string `yaml:"columns,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	path    string
+	current Config
+)
+
+// DefaultPath is where the config lives unless told otherwise, empty when
+// the system has no config directory.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "yadc", "config.yaml")
+}
+
+// Load reads the config at file, which need not exist yet. Later saves go
+// to the same file.
+func Load(file string) error {
+	logger.Trace(file)
+	mu.Lock()
+	defer mu.Unlock()
+
+	path = file
+	current = Config{}
+	if file == "" {
+		return nil
+	}
+
+	buf, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(buf, &current)
+}
+
+func save() error {
+	if path == "" {
+		return errors.New("no config file to save to")
+	}
+	buf, err := yaml.Marshal(current)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// write aside then rename so a crash never leaves half a config
+	partial := path + ".partial"
+	if err := os.WriteFile(partial, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(partial, path)
+}
+
+// Columns returns the columns chosen for mode, nil when none were.
+func Columns(mode string) []string {
+	mu.Lock()
+	defer mu.Unlock()
+	return slices.Clone(current.Columns[mode])
+}
+
+// SetColumns records the columns chosen for mode and saves the config, nil
+// going back to the defaults.
+func SetColumns(mode string, columns []string) error {
+	logger.Trace(mode, columns)
+	mu.Lock()
+	defer mu.Unlock()
+
+	if columns == nil {
+		delete(current.Columns, mode)
+	} else {
+		if current.Columns == nil {
+			current.Columns = make(map[string][]string)
+		}
+		current.Columns[mode] = slices.Clone(columns)
+	}
+	return save()
+}
@@ -25,16 +25,9 @@ func (m *Model) ConfirmActions(msg tea.KeyMsg) bool {
 	return false
 }
 
-func (m Model) ConfirmDialog() string {
-	dialogBoxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#874BFD")).
-		Padding(1, 0).
-		BorderTop(true).
-		BorderLeft(true).
-		BorderRight(true).
-		BorderBottom(true)
-
+// confirmParts renders the pieces of the dialog, apart so ButtonAt can
+// tell where the buttons ended up.
+func (m Model) confirmParts() (title string, question string, buttons []string) {
 	buttonStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#FFF7DB")).
 		Background(lipgloss.Color("#888B7E")).
@@ -48,7 +41,6 @@ func (m Model) ConfirmDialog() string {
 		MarginRight(2).
 		Underline(true)
 
-	buttons := []string{}
 	for i, lbl := range m.buttons {
 		var s lipgloss.Style
 		if i == m.selected {
@@ -59,10 +51,60 @@ func (m Model) ConfirmDialog() string {
 		buttons = append(buttons, s.Render(lbl))
 	}
 
-	title := lipgloss.NewStyle().Width(50).Bold(true).Align(lipgloss.Center).Render(m.title)
-	question := lipgloss.NewStyle().Width(50).Align(lipgloss.Center).Render(m.message)
+	title = lipgloss.NewStyle().Width(dialogWidth).Bold(true).Align(lipgloss.Center).Render(m.title)
+	question = lipgloss.NewStyle().Width(dialogWidth).Align(lipgloss.Center).Render(m.message)
+	return title, question, buttons
+}
+
+func (m Model) ConfirmDialog() string {
+	dialogBoxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#874BFD")).
+		Padding(1, 0).
+		BorderTop(true).
+		BorderLeft(true).
+		BorderRight(true).
+		BorderBottom(true)
+
+	title, question, buttons := m.confirmParts()
 	buttonBar := lipgloss.JoinHorizontal(lipgloss.Top, buttons...)
 	ui := lipgloss.JoinVertical(lipgloss.Center, title, question, buttonBar)
 
 	return dialogBoxStyle.Render(ui)
 }
+
+// ButtonAt returns the button at x, y counted from the top left corner of
+// the dialog, -1 when there is none.
+func (m Model) ButtonAt(x int, y int) int {
+	title, question, buttons := m.confirmParts()
+
+	// border and padding, then the button margin
+	top := 2 + lipgloss.Height(title) + lipgloss.Height(question) + 1
+	if y != top {
+		return -1
+	}
+
+	bar := 0
+	for _, b := range buttons {
+		bar += lipgloss.Width(b)
+	}
+	// centering rounds the gap on the left up
+	left := 1 + (dialogWidth-bar+1)/2
+	for i, b := range buttons {
+		// the margin right of the button does not count
+		if x >= left && x < left+lipgloss.Width(b)-2 {
+			return i
+		}
+		left += lipgloss.Width(b)
+	}
+	return -1
+}
+
+// Click picks the button and confirms the dialog.
+func (m *Model) Click(button int) {
+	if button < 0 || button >= len(m.buttons) {
+		return
+	}
+	m.selected = button
+	m.confirmation = true
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/muesli/termenv"
 )
 
+const dialogWidth = 50
+
 type Model struct {
 	title        string
 	message      string
@@ -41,6 +43,8 @@ func NewDialog(title string, message string, buttons ...string) Model {
 func (m Model) Selected() int   { return m.selected }
 func (m Model) Confirmed() bool { return m.confirmation }
 
+func (m *Model) SetMessage(message string) { m.message = message }
+
 // Most of this code is borrowed from
 // https://github.com/charmbracelet/lipgloss/pull/102
 // as well as the lipgloss library, with some modification for what I needed.
@@ -0,0 +1,177 @@
+package dialog
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/presselam/yadc/internal/logger"
+	"strings"
+)
+
+const formWidth = 60
+
+var (
+	KeyNext   = key.NewBinding(key.WithKeys("tab", "down"))
+	KeyPrev   = key.NewBinding(key.WithKeys("shift+tab", "up"))
+	KeyEnter  = key.NewBinding(key.WithKeys("enter"))
+	KeySubmit = key.NewBinding(key.WithKeys("ctrl+s"))
+	KeyCancel = key.NewBinding(key.WithKeys("esc"))
+)
+
+// Field is a single labelled text input of a Form.
+type Field struct {
+	Label string
+	input textinput.Model
+}
+
+// Form is a dialog of text inputs. Tab and shift+tab move between fields,
+// enter on the last field or ctrl+s submits and esc cancels.
+type Form struct {
+	title     string
+	fields    []Field
+	focused   int
+	err       string
+	preview   func(Form) string
+	submitted bool
+	cancelled bool
+}
+
+func NewField(label string, value string, placeholder string) Field {
+	input := textinput.New()
+	input.Prompt = ""
+	input.Placeholder = placeholder
+	input.Width = formWidth - 20
+	input.SetValue(value)
+
+	return Field{Label: label, input: input}
+}
+
+func NewForm(title string, fields ...Field) Form {
+	m := Form{
+		title:  title,
+		fields: fields,
+	}
+	m.focus(0)
+
+	return m
+}
+
+// SetPreview sets a function rendering a line under the fields, refreshed
+// on every key press.
+func (m *Form) SetPreview(preview func(Form) string) { m.preview = preview }
+
+// SetError shows err under the fields and reopens the form for editing.
+func (m *Form) SetError(err error) {
+	m.err = ""
+	if err != nil {
+		m.err = err.Error()
+	}
+	m.submitted = false
+}
+
+func (m Form) Submitted() bool { return m.submitted }
+func (m Form) Cancelled() bool { return m.cancelled }
+
+// Value returns the trimmed contents of the field with label.
+func (m Form) Value(label string) string {
+	for _, f := range m.fields {
+		if f.Label == label {
+			return strings.TrimSpace(f.input.Value())
+		}
+	}
+	return ""
+}
+
+func (m *Form) focus(index int) {
+	if len(m.fields) == 0 {
+		return
+	}
+	m.fields[m.focused].input.Blur()
+	m.focused = (index + len(m.fields)) % len(m.fields)
+	m.fields[m.focused].input.Focus()
+}
+
+func (m Form) Update(msg tea.Msg) (Form, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	logger.Debug("dialog.form.update:", keyMsg.String())
+
+	switch {
+	case key.Matches(keyMsg, KeyCancel):
+		m.cancelled = true
+		return m, nil
+	case key.Matches(keyMsg, KeySubmit):
+		m.submitted = true
+		return m, nil
+	case key.Matches(keyMsg, KeyEnter):
+		if m.focused == len(m.fields)-1 {
+			m.submitted = true
+			return m, nil
+		}
+		m.focus(m.focused + 1)
+		return m, nil
+	case key.Matches(keyMsg, KeyNext):
+		m.focus(m.focused + 1)
+		return m, nil
+	case key.Matches(keyMsg, KeyPrev):
+		m.focus(m.focused - 1)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.fields[m.focused].input, cmd = m.fields[m.focused].input.Update(msg)
+	return m, cmd
+}
+
+func (m Form) View() string {
+	dialogBoxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#874BFD")).
+		Padding(1, 1)
+
+	labelStyle := lipgloss.NewStyle().
+		Width(16).
+		Foreground(lipgloss.Color("70"))
+
+	activeLabelStyle := labelStyle.
+		Foreground(lipgloss.Color("#F25D94")).
+		Bold(true)
+
+	lines := []string{
+		lipgloss.NewStyle().Width(formWidth).Bold(true).Align(lipgloss.Center).Render(m.title),
+		"",
+	}
+
+	for i, f := range m.fields {
+		style := labelStyle
+		if i == m.focused {
+			style = activeLabelStyle
+		}
+		lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, style.Render(f.Label), f.input.View()))
+	}
+
+	if m.preview != nil {
+		preview := lipgloss.NewStyle().
+			Width(formWidth).
+			Foreground(lipgloss.Color("241")).
+			Render(m.preview(m))
+		lines = append(lines, "", preview)
+	}
+
+	if m.err != "" {
+		errStyle := lipgloss.NewStyle().
+			Width(formWidth).
+			Foreground(lipgloss.Color("196"))
+		lines = append(lines, "", errStyle.Render(m.err))
+	}
+
+	help := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Render("tab: next  shift+tab: previous  ctrl+s: submit  esc: cancel")
+	lines = append(lines, "", help)
+
+	return dialogBoxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
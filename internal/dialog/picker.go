@@ -0,0 +1,135 @@
+package dialog
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/presselam/yadc/internal/logger"
+	"strings"
+)
+
+const pickerWidth = 40
+
+var (
+	KeyToggle    = key.NewBinding(key.WithKeys(" ", "x"))
+	KeyMoveUp    = key.NewBinding(key.WithKeys("shift+up", "K"))
+	KeyMoveDown  = key.NewBinding(key.WithKeys("shift+down", "J"))
+	KeyPickDown  = key.NewBinding(key.WithKeys("down", "j"))
+	KeyPickUp    = key.NewBinding(key.WithKeys("up", "k"))
+	KeyPickReset = key.NewBinding(key.WithKeys("r"))
+)
+
+// PickerItem is a line of a Picker.
+type PickerItem struct {
+	Label   string
+	Checked bool
+}
+
+// Picker is a dialog for choosing items and putting them in order. Space
+// checks an item, shift+up and shift+down move it, enter submits, r asks
+// for the defaults and esc cancels.
+type Picker struct {
+	title     string
+	items     []PickerItem
+	cursor    int
+	submitted bool
+	cancelled bool
+	reset     bool
+}
+
+func NewPicker(title string, items ...PickerItem) Picker {
+	return Picker{title: title, items: items}
+}
+
+func (m Picker) Submitted() bool { return m.submitted }
+func (m Picker) Cancelled() bool { return m.cancelled }
+func (m Picker) Reset() bool     { return m.reset }
+
+// Checked returns the labels of the checked items, in order.
+func (m Picker) Checked() []string {
+	retval := []string{}
+	for _, item := range m.items {
+		if item.Checked {
+			retval = append(retval, item.Label)
+		}
+	}
+	return retval
+}
+
+func (m Picker) Update(msg tea.Msg) (Picker, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || len(m.items) == 0 {
+		return m, nil
+	}
+	logger.Debug("dialog.picker.update:", keyMsg.String())
+
+	switch {
+	case key.Matches(keyMsg, KeyCancel):
+		m.cancelled = true
+	case key.Matches(keyMsg, KeyEnter):
+		m.submitted = true
+	case key.Matches(keyMsg, KeyPickReset):
+		m.reset = true
+		m.submitted = true
+	case key.Matches(keyMsg, KeyToggle):
+		m.items[m.cursor].Checked = !m.items[m.cursor].Checked
+	case key.Matches(keyMsg, KeyMoveUp):
+		if m.cursor > 0 {
+			m.items[m.cursor], m.items[m.cursor-1] = m.items[m.cursor-1], m.items[m.cursor]
+			m.cursor--
+		}
+	case key.Matches(keyMsg, KeyMoveDown):
+		if m.cursor < len(m.items)-1 {
+			m.items[m.cursor], m.items[m.cursor+1] = m.items[m.cursor+1], m.items[m.cursor]
+			m.cursor++
+		}
+	case key.Matches(keyMsg, KeyPickUp):
+		m.cursor = (m.cursor - 1 + len(m.items)) % len(m.items)
+	case key.Matches(keyMsg, KeyPickDown):
+		m.cursor = (m.cursor + 1) % len(m.items)
+	}
+	return m, nil
+}
+
+func (m Picker) View() string {
+	dialogBoxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#874BFD")).
+		Padding(1, 1)
+
+	itemStyle := lipgloss.NewStyle().
+		Width(pickerWidth).
+		Foreground(lipgloss.Color("70"))
+
+	activeItemStyle := itemStyle.
+		Foreground(lipgloss.Color("#F25D94")).
+		Bold(true)
+
+	lines := []string{
+		lipgloss.NewStyle().Width(pickerWidth).Bold(true).Align(lipgloss.Center).Render(m.title),
+		"",
+	}
+
+	for i, item := range m.items {
+		style := itemStyle
+		if i == m.cursor {
+			style = activeItemStyle
+		}
+		check := "[ ] "
+		if item.Checked {
+			check = "[x] "
+		}
+		lines = append(lines, style.Render(check+item.Label))
+	}
+
+	help := lipgloss.NewStyle().
+		Width(pickerWidth).
+		Foreground(lipgloss.Color("241")).
+		Render(strings.Join([]string{
+			"space: show/hide  shift+↑/↓: move",
+			"enter: save  r: defaults  esc: cancel",
+		}, "\n"))
+	lines = append(lines, "", help)
+
+	return dialogBoxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
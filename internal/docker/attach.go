@@ -0,0 +1,157 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/moby/term"
+	"github.com/muesli/cancelreader"
+	"github.com/presselam/yadc/internal/logger"
+	"io"
+	"os"
+	"strings"
+)
+
+const DefaultDetachKeys = "ctrl-p,ctrl-q"
+
+var detachKeys = DefaultDetachKeys
+
+// SetDetachKeys sets the key sequence that ends an attach session, in the
+// same notation as docker attach --detach-keys.
+func SetDetachKeys(keys string) error {
+	if keys == "" {
+		keys = DefaultDetachKeys
+	}
+	if _, err := term.ToBytes(keys); err != nil {
+		return err
+	}
+	detachKeys = keys
+	return nil
+}
+
+// AttachCmd connects the terminal to the main process of a container. It
+// satisfies tea.ExecCommand so the program can hand the screen over while
+// attached and take it back on detach.
+type AttachCmd struct {
+	id     string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func ContainerAttach(id string) *AttachCmd {
+	return &AttachCmd{
+		id:     id,
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+}
+
+func (c *AttachCmd) SetStdin(r io.Reader)  { c.stdin = r }
+func (c *AttachCmd) SetStdout(w io.Writer) { c.stdout = w }
+func (c *AttachCmd) SetStderr(w io.Writer) { c.stderr = w }
+
+func (c *AttachCmd) Run() error {
+	logger.Trace(c.id)
+
+	escape, err := term.ToBytes(detachKeys)
+	if err != nil {
+		return err
+	}
+
+	docker, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer docker.Close()
+
+	inspect, err := docker.ContainerInspect(context.Background(), c.id)
+	if err != nil {
+		return err
+	}
+	if !inspect.State.Running {
+		return errors.New("container is not running: [" + c.id + "]")
+	}
+	tty := inspect.Config.Tty
+
+	resp, err := docker.ContainerAttach(context.Background(), c.id, container.AttachOptions{
+		Stream: true,
+		Stdin:  inspect.Config.OpenStdin,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	if fd, isTerminal := term.GetFdInfo(c.stdin); isTerminal && tty {
+		state, err := term.SetRawTerminal(fd)
+		if err != nil {
+			return err
+		}
+		defer term.RestoreTerminal(fd, state)
+
+		if size, err := term.GetWinsize(fd); err == nil {
+			err = docker.ContainerResize(context.Background(), c.id, container.ResizeOptions{
+				Height: uint(size.Height),
+				Width:  uint(size.Width),
+			})
+			if err != nil {
+				logger.Warn("docker.attach.resize:", err)
+			}
+		}
+	}
+
+	newline := "\n"
+	if tty {
+		newline = "\r\n"
+	}
+	fmt.Fprintf(c.stdout, "attached to %s, detach with %s%s", strings.TrimPrefix(inspect.Name, "/"), detachKeys, newline)
+
+	// the reader is cancelled on the way out, otherwise it would swallow
+	// the next key press meant for the table
+	input, err := cancelreader.NewReader(c.stdin)
+	if err != nil {
+		return err
+	}
+	defer input.Cancel()
+
+	done := make(chan error, 2)
+	go func() {
+		var err error
+		if tty {
+			_, err = io.Copy(c.stdout, resp.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(c.stdout, c.stderr, resp.Reader)
+		}
+		done <- err
+	}()
+	go func() {
+		// without an open stdin keys are only watched for the detach sequence
+		var w io.Writer = resp.Conn
+		if !inspect.Config.OpenStdin {
+			w = io.Discard
+		}
+		_, err := io.Copy(w, term.NewEscapeProxy(input, escape))
+		var detach term.EscapeError
+		if errors.As(err, &detach) {
+			done <- nil
+			return
+		}
+		if errors.Is(err, cancelreader.ErrCanceled) {
+			return
+		}
+		resp.CloseWrite()
+	}()
+
+	err = <-done
+	fmt.Fprint(c.stdout, newline)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
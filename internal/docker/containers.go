@@ -7,54 +7,78 @@ import (
 	"fmt"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
-	"github.com/docker/docker/client"
 	"github.com/presselam/yadc/internal/bubble"
+	"github.com/presselam/yadc/internal/logger"
 	"io"
 	"log"
 	//	"os"
-	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-func Containers() (Results, error) {
+// Containers lists every container with all the fields there are columns
+// for. Sizes take the engine a while to work out, they are left empty
+// unless withSize.
+func Containers(withSize bool) (Results, error) {
 	retval := Results{
-		[]string{"ID", "Name", "Image", "State", "Ports"},
+		[]string{"ID", "Name", "Image", "State", "Ports", "Status", "Created", "Command", "Size", "Networks", "Mounts", "Labels"},
 		[][]string{},
-		[]int{0, 0, 0, 0, 0},
+		[]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
 	}
 
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		return retval, err
 	}
 	defer docker.Close()
 
-	containers, err := docker.ContainerList(context.Background(), container.ListOptions{All: true})
+	containers, err := docker.ContainerList(context.Background(), container.ListOptions{All: true, Size: withSize})
 	if err != nil {
 		return retval, err
 	}
 
 	for _, cont := range containers {
-		var row []string
-		if len(cont.Names) == 0 {
-			row = []string{
-				cont.ID[0:8],
-				"<none>",
-				cont.Image,
-				cont.State,
-				displayPorts(cont.Ports),
+		name := "<none>"
+		if len(cont.Names) > 0 {
+			name = cont.Names[len(cont.Names)-1][1:]
+		}
+
+		size := ""
+		if withSize {
+			size = strconv.FormatInt(cont.SizeRw, 10)
+		}
+
+		var networks []string
+		if cont.NetworkSettings != nil {
+			for network := range cont.NetworkSettings.Networks {
+				networks = append(networks, network)
 			}
-		} else {
-			for _, name := range cont.Names {
-				row = []string{
-					cont.ID[0:8],
-					name[1:],
-					cont.Image,
-					cont.State,
-					displayPorts(cont.Ports),
-				}
+			sort.Strings(networks)
+		}
+
+		var mounts []string
+		for _, m := range cont.Mounts {
+			source := m.Name
+			if source == "" {
+				source = m.Source
 			}
+			mounts = append(mounts, source+":"+m.Destination)
+		}
+
+		row := []string{
+			cont.ID,
+			name,
+			cont.Image,
+			cont.State,
+			displayPorts(cont.Ports),
+			cont.Status,
+			strconv.FormatInt(cont.Created, 10),
+			cont.Command,
+			size,
+			strings.Join(networks, ","),
+			strings.Join(mounts, ","),
+			displayLabels(cont.Labels),
 		}
 		retval.Data = append(retval.Data, row)
 
@@ -69,7 +93,7 @@ func Containers() (Results, error) {
 }
 
 func ContainerStop(id string) error {
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		return err
 	}
@@ -84,7 +108,7 @@ func ContainerStop(id string) error {
 }
 
 func ContainerRestart(id string) error {
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		return err
 	}
@@ -98,123 +122,96 @@ func ContainerRestart(id string) error {
 	return nil
 }
 
-func ContainerInspect(id string) (Results, error) {
-	retval := Results{
-		[]string{"Name", "Value"},
-		[][]string{},
-		[]int{0, 0},
+// displayLabels joins labels as key=value pairs, sorted by key.
+func displayLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
 	}
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ContainerRemove removes a container, stopping it first when it runs.
+func ContainerRemove(id string) error {
+	docker, err := newClient()
 	if err != nil {
-		return retval, err
+		return err
 	}
 	defer docker.Close()
 
-	inspect, err := docker.ContainerInspect(context.Background(), id)
+	err = docker.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
 	if err != nil {
-		return retval, err
-	}
-
-	rows := printObject(inspect.ContainerJSONBase, 0)
-	for _, row := range rows {
-		retval.Data = append(retval.Data, row)
-		for i, val := range row {
-			if len(val) > retval.Width[i] {
-				retval.Width[i] = len(val)
-			}
-		}
+		return err
 	}
 
-	//	log.Printf("inspect.Mounts:[%v]", inspect.Mounts)
-	//	log.Printf("inspect.Config:[%v]", inspect.Config)
-	//	log.Printf("inspect.NetworkSettings:[%v]", inspect.NetworkSettings)
-	//	log.Printf("inspect.ImageManifestDescriptor:[%v]", inspect.ImageManifestDescriptor)
+	return nil
+}
 
-	return retval, nil
+// CommitSpec holds the settings of an image committed from a container.
+// Changes are Dockerfile instructions in shell style quoting, e.g.
+// `"ENV DEBUG=1" "EXPOSE 8080"`.
+type CommitSpec struct {
+	Reference string
+	Author    string
+	Message   string
+	Pause     string
+	Changes   string
 }
 
-func printObject(obj any, depth int) []bubble.Row {
-	var retval []bubble.Row
+// ContainerCommit snapshots a container into a new image, returning the
+// image ID.
+func ContainerCommit(id string, spec CommitSpec) (string, error) {
+	logger.Trace(id, spec.Reference)
 
-	v := reflect.ValueOf(obj)
-	if v.Kind() == reflect.Pointer {
-		v = v.Elem()
+	changes, err := SplitArgs(spec.Changes)
+	if err != nil {
+		return "", err
 	}
-	t := v.Type()
 
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		name := t.Field(i).Name
-		//    log.Printf("fld:[%d][%d] => %s:%v", depth, i, name, field.Interface())
+	pause := true
+	switch strings.ToLower(spec.Pause) {
+	case "", "y", "yes", "true":
+	case "n", "no", "false":
+		pause = false
+	default:
+		return "", errors.New("invalid pause: [" + spec.Pause + "]")
+	}
 
-		value := convertValue(name, field, depth)
-		for _, ln := range value {
-			retval = append(retval, ln)
-		}
+	docker, err := newClient()
+	if err != nil {
+		return "", err
 	}
-	return retval
+	defer docker.Close()
+
+	response, err := docker.ContainerCommit(context.Background(), id, container.CommitOptions{
+		Reference: spec.Reference,
+		Comment:   spec.Message,
+		Author:    spec.Author,
+		Changes:   changes,
+		Pause:     pause,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return response.ID, nil
 }
 
-func convertValue(name string, field reflect.Value, depth int) []bubble.Row {
-	padd := fmt.Sprintf("%"+strconv.Itoa(4*depth)+"s", "")
-	var retval []bubble.Row
-	switch field.Kind() {
-	case reflect.String:
-		row := bubble.Row{padd + name, field.String()}
-		retval = append(retval, row)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		row := bubble.Row{padd + name, strconv.FormatInt(field.Int(), 10)}
-		retval = append(retval, row)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		row := bubble.Row{padd + name, strconv.FormatUint(field.Uint(), 10)}
-		retval = append(retval, row)
-	case reflect.Slice, reflect.Array:
-		var sliceValues []string
-		value := ""
-		if field.Len() > 0 {
-			for j := 0; j < field.Len(); j++ {
-				sliceValues = append(sliceValues, fmt.Sprint(field.Index(j).Interface()))
-			}
-			value = "[" + fmt.Sprint(strings.Join(sliceValues, ", ")) + "]"
-		}
-		row := bubble.Row{padd + name, value}
-		retval = append(retval, row)
-	case reflect.Pointer:
-		if !field.IsNil() {
-			retval = append(retval, bubble.Row{padd + name, ""})
-			lines := printObject(field.Interface(), depth+1)
-			for _, ln := range lines {
-				retval = append(retval, ln)
-			}
-		}
-	case reflect.Struct:
-		retval = append(retval, bubble.Row{padd + name, ""})
-		lines := printObject(field.Interface(), depth+1)
-		for _, ln := range lines {
-			retval = append(retval, ln)
-		}
-	case reflect.Bool:
-		value := fmt.Sprintf("%v", field.Bool())
-		row := bubble.Row{padd + name, value}
-		retval = append(retval, row)
-	case reflect.Map:
-		retval = append(retval, bubble.Row{padd + name + "{", ""})
-		keys := field.MapKeys()
-		for _, key := range keys {
-			log.Printf("map[%v] => [%v]", key, field.MapIndex(key))
-			retval = append(retval, bubble.Row{padd + key.String(), ""})
-			lines := convertValue(name, field.MapIndex(key), depth+1)
-			for _, ln := range lines {
-				retval = append(retval, ln)
-			}
-		}
-		retval = append(retval, bubble.Row{padd + "}", ""})
-	default:
-		value := fmt.Sprintf("TODO: [%v]", field.Kind())
-		row := bubble.Row{padd + name, value}
-		retval = append(retval, row)
+// ContainerInspect returns everything the engine knows of the container.
+func ContainerInspect(id string) (Inspection, error) {
+	docker, err := newClient()
+	if err != nil {
+		return Inspection{}, err
 	}
-	return retval
+	defer docker.Close()
+
+	inspect, raw, err := docker.ContainerInspectWithRaw(context.Background(), id, false)
+	if err != nil {
+		return Inspection{}, err
+	}
+
+	return Inspection{Tree: InspectTree(inspect), Raw: raw}, nil
 }
 
 func displayPorts(ports []container.Port) string {
@@ -236,7 +233,7 @@ func displayPorts(ports []container.Port) string {
 }
 
 func ContainerPrune(id string) error {
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		return err
 	}
@@ -257,7 +254,7 @@ func ContainerLog(id string, since string) (Results, error) {
 		[][]string{},
 		[]int{0},
 	}
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		log.Println("docker.container.log.client.err:", err)
 		return retval, err
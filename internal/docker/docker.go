@@ -2,7 +2,7 @@ package docker
 
 import (
 	"context"
-	"github.com/docker/docker/client"
+	"github.com/docker/docker/api"
 )
 
 type ServerInfo struct {
@@ -14,6 +14,9 @@ type ServerInfo struct {
 	Name          string
 	ServerVersion string
 	ClientVersion string
+	Engine        string
+	APIVersion    string
+	Host          string
 }
 
 type Results struct {
@@ -23,12 +26,13 @@ type Results struct {
 }
 
 func Info() (ServerInfo, error) {
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
-		panic(err)
+		return ServerInfo{}, err
 	}
 	defer docker.Close()
 
+	current, _ := Detect()
 	info, err := docker.Info(context.Background())
 	retval := ServerInfo{
 		info.ID,
@@ -38,7 +42,12 @@ func Info() (ServerInfo, error) {
 		info.Images,
 		info.Name,
 		info.ServerVersion,
-		docker.ClientVersion(),
+		// the client is pinned to the engine's API, report the newest
+		// one the library speaks instead
+		api.DefaultVersion,
+		current.Flavor,
+		current.APIVersion,
+		current.Host,
 	}
 
 	return retval, err
@@ -0,0 +1,236 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/client"
+	"github.com/presselam/yadc/internal/logger"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FlavorDocker        = "docker"
+	FlavorRootless      = "docker (rootless)"
+	FlavorDockerDesktop = "docker desktop"
+	FlavorPodman        = "podman"
+	FlavorColima        = "colima"
+	FlavorRancher       = "rancher desktop"
+	FlavorOrbStack      = "orbstack"
+
+	probeTimeout = 3 * time.Second
+)
+
+// Feature names an engine capability that is not available everywhere.
+type Feature string
+
+const (
+	FeatureManifests Feature = "manifests"
+)
+
+// minimum API version of each feature
+var featureVersions = map[Feature]string{
+	FeatureManifests: "1.47",
+}
+
+// features podman's compat API does not implement whatever version it
+// reports
+var podmanMissing = map[Feature]bool{
+	FeatureManifests: true,
+}
+
+// ConnectionMsg reports the engine going away or coming back.
+type ConnectionMsg struct {
+	Connected bool
+	Err       error
+}
+
+// Engine describes the daemon yadc is talking to.
+type Engine struct {
+	Host       string
+	Flavor     string
+	APIVersion string
+}
+
+var (
+	engineLock sync.Mutex
+	engine     *Engine
+)
+
+// Detect finds a responding engine, trying DOCKER_HOST when it is set and
+// the well known sockets of rootless docker, podman, colima, rancher
+// desktop and friends otherwise. The result is cached until Reset.
+func Detect() (Engine, error) {
+	engineLock.Lock()
+	defer engineLock.Unlock()
+
+	if engine != nil {
+		return *engine, nil
+	}
+
+	err := errors.New("no container engine found")
+	for _, host := range candidateHosts() {
+		var found Engine
+		found, err = probe(host)
+		if err != nil {
+			logger.Debug("docker.engine.probe:", host, " - ", err)
+			continue
+		}
+
+		logger.Info("Engine: ", found.Flavor, " API ", found.APIVersion, " at ", found.Host)
+		engine = &found
+		return found, nil
+	}
+
+	return Engine{}, err
+}
+
+// Reset forgets the detected engine so the next call probes again.
+func Reset() {
+	engineLock.Lock()
+	defer engineLock.Unlock()
+	engine = nil
+}
+
+// Supports reports whether the detected engine provides feature.
+func Supports(feature Feature) bool {
+	if feature == "" {
+		return true
+	}
+
+	current, err := Detect()
+	if err != nil {
+		return false
+	}
+	if current.Flavor == FlavorPodman && podmanMissing[feature] {
+		return false
+	}
+	if min, ok := featureVersions[feature]; ok && versions.LessThan(current.APIVersion, min) {
+		return false
+	}
+	return true
+}
+
+// newClient creates a client for the detected engine using the negotiated
+// API version.
+func newClient() (*client.Client, error) {
+	current, err := Detect()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.Opt{client.FromEnv, client.WithHost(current.Host)}
+	if os.Getenv(client.EnvOverrideAPIVersion) == "" {
+		opts = append(opts, client.WithVersion(current.APIVersion))
+	}
+	return client.NewClientWithOpts(opts...)
+}
+
+func candidateHosts() []string {
+	if host := os.Getenv(client.EnvOverrideHost); host != "" {
+		return []string{host}
+	}
+
+	retval := []string{client.DefaultDockerHost}
+
+	var sockets []string
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		sockets = append(sockets,
+			filepath.Join(runtime, "docker.sock"),
+			filepath.Join(runtime, "podman", "podman.sock"),
+		)
+	}
+	sockets = append(sockets, "/run/podman/podman.sock")
+	if home, err := os.UserHomeDir(); err == nil {
+		sockets = append(sockets,
+			filepath.Join(home, ".docker", "run", "docker.sock"),
+			filepath.Join(home, ".colima", "default", "docker.sock"),
+			filepath.Join(home, ".colima", "docker.sock"),
+			rancherSocket(home),
+			filepath.Join(home, ".orbstack", "run", "docker.sock"),
+			filepath.Join(home, ".local", "share", "containers", "podman", "machine", "podman.sock"),
+		)
+	}
+
+	for _, socket := range sockets {
+		if _, err := os.Stat(socket); err == nil {
+			retval = append(retval, "unix://"+socket)
+		}
+	}
+
+	return retval
+}
+
+// rancherSocket is where Rancher Desktop puts the engine socket.
+func rancherSocket(home string) string {
+	return filepath.Join(home, ".rd", "docker.sock")
+}
+
+func isRancher(host string) bool {
+	home, err := os.UserHomeDir()
+	return err == nil && host == "unix://"+rancherSocket(home)
+}
+
+func probe(host string) (Engine, error) {
+	retval := Engine{Host: host}
+
+	docker, err := client.NewClientWithOpts(client.FromEnv, client.WithHost(host))
+	if err != nil {
+		return retval, err
+	}
+	defer docker.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	ping, err := docker.Ping(ctx)
+	if err != nil {
+		return retval, err
+	}
+	docker.NegotiateAPIVersionPing(ping)
+	retval.APIVersion = docker.ClientVersion()
+	retval.Flavor = engineFlavor(ctx, docker, host)
+
+	return retval, nil
+}
+
+func engineFlavor(ctx context.Context, docker *client.Client, host string) string {
+	version, err := docker.ServerVersion(ctx)
+	if err == nil {
+		for _, component := range version.Components {
+			if strings.Contains(strings.ToLower(component.Name), "podman") {
+				return FlavorPodman
+			}
+		}
+	}
+
+	switch {
+	case strings.Contains(host, "podman"):
+		return FlavorPodman
+	case strings.Contains(host, ".colima"):
+		return FlavorColima
+	case isRancher(host):
+		return FlavorRancher
+	case strings.Contains(host, ".orbstack"):
+		return FlavorOrbStack
+	}
+
+	info, err := docker.Info(ctx)
+	if err != nil {
+		return FlavorDocker
+	}
+	for _, option := range info.SecurityOptions {
+		if strings.Contains(option, "name=rootless") {
+			return FlavorRootless
+		}
+	}
+	if strings.Contains(info.OperatingSystem, "Docker Desktop") {
+		return FlavorDockerDesktop
+	}
+
+	return FlavorDocker
+}
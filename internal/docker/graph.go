@@ -0,0 +1,280 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/presselam/yadc/internal/logger"
+	"sort"
+	"strings"
+)
+
+type NodeKind string
+
+const (
+	NodeContainer NodeKind = "container"
+	NodeImage     NodeKind = "image"
+	NodeNetwork   NodeKind = "network"
+	NodeVolume    NodeKind = "volume"
+	NodeBind      NodeKind = "bind"
+	NodeProject   NodeKind = "project"
+
+	composeProject   = "com.docker.compose.project"
+	composeService   = "com.docker.compose.service"
+	composeDependsOn = "com.docker.compose.depends_on"
+)
+
+// order the kinds are listed in under a node
+var kindOrder = map[NodeKind]int{
+	NodeProject:   0,
+	NodeImage:     1,
+	NodeContainer: 2,
+	NodeNetwork:   3,
+	NodeVolume:    4,
+	NodeBind:      5,
+}
+
+type GraphNode struct {
+	Kind NodeKind
+	ID   string
+	Name string
+}
+
+func (n GraphNode) Key() string { return NodeKey(n.Kind, n.ID) }
+
+func NodeKey(kind NodeKind, id string) string { return string(kind) + ":" + id }
+
+type graphEdge struct {
+	to       string
+	relation string
+}
+
+// Graph holds how containers are wired to images, networks, volumes, bind
+// mounts, compose projects and each other.
+type Graph struct {
+	nodes map[string]GraphNode
+	edges map[string][]graphEdge
+}
+
+// GraphRow is a line of the tree drawn around a node.
+type GraphRow struct {
+	Node     GraphNode
+	Relation string
+	Prefix   string
+}
+
+func (g *Graph) add(node GraphNode) string {
+	key := node.Key()
+	if existing, ok := g.nodes[key]; !ok || existing.Name == "" {
+		g.nodes[key] = node
+	}
+	return key
+}
+
+// link connects two nodes, relation is read from a towards b and reverse
+// from b towards a.
+func (g *Graph) link(a string, b string, relation string, reverse string) {
+	g.edges[a] = append(g.edges[a], graphEdge{b, relation})
+	g.edges[b] = append(g.edges[b], graphEdge{a, reverse})
+}
+
+func (g Graph) Node(key string) (GraphNode, bool) {
+	node, ok := g.nodes[key]
+	return node, ok
+}
+
+// ResourceGraph collects the relationships of every container.
+func ResourceGraph() (Graph, error) {
+	logger.Trace()
+	retval := Graph{
+		nodes: make(map[string]GraphNode),
+		edges: make(map[string][]graphEdge),
+	}
+
+	docker, err := newClient()
+	if err != nil {
+		return retval, err
+	}
+	defer docker.Close()
+
+	images, err := docker.ImageList(context.Background(), image.ListOptions{All: true})
+	if err != nil {
+		return retval, err
+	}
+	for _, img := range images {
+		name := imageNone
+		if len(img.RepoTags) > 0 {
+			name = img.RepoTags[0]
+		}
+		retval.add(GraphNode{NodeImage, ShortID(img.ID), name})
+	}
+
+	containers, err := docker.ContainerList(context.Background(), container.ListOptions{All: true})
+	if err != nil {
+		return retval, err
+	}
+
+	// links show up as extra names: /parent/alias
+	byName := make(map[string]string)
+	byService := make(map[string]string)
+	for _, cont := range containers {
+		key := NodeKey(NodeContainer, cont.ID[0:8])
+		for _, name := range cont.Names {
+			if strings.Count(name, "/") == 1 {
+				byName[name[1:]] = key
+			}
+		}
+		if project, ok := cont.Labels[composeProject]; ok {
+			byService[project+"/"+cont.Labels[composeService]] = key
+		}
+	}
+
+	for _, cont := range containers {
+		name := "<none>"
+		var links []string
+		for _, n := range cont.Names {
+			if strings.Count(n, "/") == 1 {
+				name = n[1:]
+			} else {
+				links = append(links, n)
+			}
+		}
+		key := retval.add(GraphNode{NodeContainer, cont.ID[0:8], name})
+
+		img := retval.add(GraphNode{NodeImage, ShortID(cont.ImageID), cont.Image})
+		retval.link(key, img, "image", "used by")
+
+		if cont.NetworkSettings != nil {
+			for network, endpoint := range cont.NetworkSettings.Networks {
+				net := retval.add(GraphNode{NodeNetwork, network, network})
+				retval.link(key, net, endpoint.IPAddress, endpoint.IPAddress)
+			}
+		}
+
+		for _, m := range cont.Mounts {
+			relation := m.Destination
+			if !m.RW {
+				relation += " (ro)"
+			}
+			switch m.Type {
+			case mount.TypeVolume:
+				vol := retval.add(GraphNode{NodeVolume, m.Name, m.Name})
+				retval.link(key, vol, relation, relation)
+			case mount.TypeBind:
+				bind := retval.add(GraphNode{NodeBind, m.Source, m.Source})
+				retval.link(key, bind, relation, relation)
+			}
+		}
+
+		project, ok := cont.Labels[composeProject]
+		if ok {
+			proj := retval.add(GraphNode{NodeProject, project, project})
+			service := cont.Labels[composeService]
+			retval.link(proj, key, service, "project")
+
+			// service:condition:restart,...
+			for _, dep := range strings.Split(cont.Labels[composeDependsOn], ",") {
+				fields := strings.Split(dep, ":")
+				target, ok := byService[project+"/"+fields[0]]
+				if fields[0] == "" || !ok {
+					continue
+				}
+				relation := "depends on"
+				if len(fields) > 1 {
+					relation += " (" + fields[1] + ")"
+				}
+				retval.link(key, target, relation, "required by")
+			}
+		}
+
+		for _, l := range links {
+			parts := strings.Split(strings.TrimPrefix(l, "/"), "/")
+			parent, ok := byName[parts[0]]
+			if !ok {
+				continue
+			}
+			alias := parts[len(parts)-1]
+			retval.link(parent, key, "links to "+alias, "linked as "+alias)
+		}
+	}
+
+	return retval, nil
+}
+
+// Tree lays the graph out as an indented tree centered on the node key,
+// going depth levels out. Every node shows up once, at the level closest
+// to the center.
+func (g Graph) Tree(key string, depth int) []GraphRow {
+	root, ok := g.nodes[key]
+	if !ok {
+		return nil
+	}
+
+	retval := []GraphRow{{Node: root}}
+	visited := map[string]bool{key: true}
+
+	var walk func(key string, prefix string, level int)
+	walk = func(key string, prefix string, level int) {
+		if level >= depth {
+			return
+		}
+
+		var next []graphEdge
+		for _, edge := range g.edges[key] {
+			if !visited[edge.to] {
+				visited[edge.to] = true
+				next = append(next, edge)
+			}
+		}
+		sort.SliceStable(next, func(i, j int) bool {
+			a, b := g.nodes[next[i].to], g.nodes[next[j].to]
+			if a.Kind != b.Kind {
+				return kindOrder[a.Kind] < kindOrder[b.Kind]
+			}
+			return a.Name < b.Name
+		})
+
+		for i, edge := range next {
+			branch, indent := "├─ ", "│  "
+			if i == len(next)-1 {
+				branch, indent = "└─ ", "   "
+			}
+			retval = append(retval, GraphRow{
+				Node:     g.nodes[edge.to],
+				Relation: edge.relation,
+				Prefix:   prefix + branch,
+			})
+			walk(edge.to, prefix+indent, level+1)
+		}
+	}
+	walk(key, "", 0)
+
+	return retval
+}
+
+func GraphResults(rows []GraphRow) Results {
+	retval := Results{
+		[]string{"Resource", "Kind", "Relation", "ID"},
+		[][]string{},
+		[]int{0, 0, 0, 0},
+	}
+
+	for _, r := range rows {
+		row := []string{
+			r.Prefix + r.Node.Name,
+			string(r.Node.Kind),
+			r.Relation,
+			r.Node.ID,
+		}
+		retval.Data = append(retval.Data, row)
+
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval
+}
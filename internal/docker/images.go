@@ -2,11 +2,16 @@ package docker
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/presselam/yadc/internal/logger"
 	"io"
 	"os"
@@ -14,6 +19,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
@@ -25,27 +31,27 @@ const (
 func Images() (Results, error) {
 	logger.Trace()
 	retval := Results{
-		[]string{"ID", "Name", "Contianers", "Size"},
+		[]string{"ID", "Name", "Containers", "Size", "Digest", "Platform", "Created", "Labels"},
 		[][]string{},
-		[]int{0, 0, 0, 0},
+		[]int{0, 0, 0, 0, 0, 0, 0, 0},
 	}
 
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		return retval, err
 	}
 	defer docker.Close()
 
-	images, err := docker.ImageList(context.Background(), image.ListOptions{All: true})
+	images, err := docker.ImageList(context.Background(), image.ListOptions{All: true, Manifests: Supports(FeatureManifests)})
 	if err != nil {
 		return retval, err
 	}
 
 	for _, img := range images {
-		if strings.HasPrefix(img.ID, shaPrefix) {
-			img.ID = strings.TrimPrefix(img.ID, shaPrefix)
-		}
-		img.ID = img.ID[0:8]
+		platform := imagePlatform(docker, img)
+		created := strconv.FormatInt(img.Created, 10)
+
+		img.ID = strings.TrimPrefix(img.ID, shaPrefix)
 
 		names := img.RepoTags
 		if len(names) == 0 {
@@ -58,6 +64,10 @@ func Images() (Results, error) {
 				name,
 				strconv.FormatInt(img.Containers, 10),
 				strconv.FormatInt(img.Size, 10),
+				repoDigest(img.RepoDigests, name),
+				platform,
+				created,
+				displayLabels(img.Labels),
 			}
 			retval.Data = append(retval.Data, row)
 
@@ -72,10 +82,218 @@ func Images() (Results, error) {
 	return retval, nil
 }
 
+// ShortID trims an image or container ID to the form shown in the tables.
+func ShortID(id string) string {
+	id = strings.TrimPrefix(id, shaPrefix)
+	if len(id) > 8 {
+		id = id[0:8]
+	}
+	return id
+}
+
+// platforms caches the platform of every image by ID, IDs being content
+// addressed the answer never changes. Images that could not be inspected
+// are cached as unknown rather than asked about on every refresh.
+var platforms sync.Map
+
+func imagePlatform(docker *client.Client, img image.Summary) string {
+	if val, ok := platforms.Load(img.ID); ok {
+		return val.(string)
+	}
+
+	var names []string
+	for _, manifest := range img.Manifests {
+		if manifest.Kind != image.ManifestKindImage || !manifest.Available || manifest.ImageData == nil {
+			continue
+		}
+		names = append(names, formatPlatform(manifest.ImageData.Platform))
+	}
+
+	if len(names) == 0 {
+		inspect, err := docker.ImageInspect(context.Background(), img.ID)
+		if err != nil {
+			logger.Warn("docker.images.platform:", err)
+			platforms.Store(img.ID, "")
+			return ""
+		}
+		names = append(names, formatPlatform(ocispec.Platform{
+			OS:           inspect.Os,
+			Architecture: inspect.Architecture,
+			Variant:      inspect.Variant,
+		}))
+	}
+
+	retval := strings.Join(names, ",")
+	platforms.Store(img.ID, retval)
+	return retval
+}
+
+func formatPlatform(p ocispec.Platform) string {
+	parts := []string{p.OS, p.Architecture}
+	if p.Variant != "" {
+		parts = append(parts, p.Variant)
+	}
+	return strings.Join(parts, "/")
+}
+
+// repoDigest picks the digest belonging to the repository of the tag being
+// displayed, falling back to the first digest the engine knows about.
+func repoDigest(digests []string, name string) string {
+	if len(digests) == 0 {
+		return imageNone
+	}
+
+	retval := digests[0]
+	repo := name
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		repo = name[:i]
+	}
+	for _, digest := range digests {
+		if strings.HasPrefix(digest, repo+"@") {
+			retval = digest
+			break
+		}
+	}
+
+	if i := strings.Index(retval, "@"); i >= 0 {
+		retval = retval[i+1:]
+	}
+	return shortDigest(retval)
+}
+
+func shortDigest(digest string) string {
+	hex := strings.TrimPrefix(digest, shaPrefix)
+	if len(hex) > 12 {
+		hex = hex[0:12]
+	}
+	if strings.HasPrefix(digest, shaPrefix) {
+		return shaPrefix + hex
+	}
+	return hex
+}
+
+// ImageManifests lists every platform variant of a multi-platform image
+// along with its size and whether its content is present locally. Images in
+// the classic store only report their own platform.
+func ImageManifests(id string) (Results, error) {
+	logger.Trace(id)
+	retval := Results{
+		[]string{"Platform", "Digest", "Kind", "Available", "Size", "Unpacked", "Containers"},
+		[][]string{},
+		[]int{0, 0, 0, 0, 0, 0, 0},
+	}
+
+	docker, err := newClient()
+	if err != nil {
+		return retval, err
+	}
+	defer docker.Close()
+
+	inspect, err := docker.ImageInspect(context.Background(), id, client.ImageInspectWithManifests(Supports(FeatureManifests)))
+	if err != nil {
+		return retval, err
+	}
+
+	var rows [][]string
+	for _, manifest := range inspect.Manifests {
+		platform := ""
+		unpacked := ""
+		containers := ""
+		switch {
+		case manifest.ImageData != nil:
+			platform = formatPlatform(manifest.ImageData.Platform)
+			unpacked = strconv.FormatInt(manifest.ImageData.Size.Unpacked, 10)
+			containers = strconv.Itoa(len(manifest.ImageData.Containers))
+		case manifest.AttestationData != nil:
+			platform = "for " + shortDigest(manifest.AttestationData.For.String())
+		}
+
+		rows = append(rows, []string{
+			platform,
+			shortDigest(manifest.Descriptor.Digest.String()),
+			string(manifest.Kind),
+			strconv.FormatBool(manifest.Available),
+			strconv.FormatInt(manifest.Size.Total, 10),
+			unpacked,
+			containers,
+		})
+	}
+
+	if len(rows) == 0 {
+		digest := inspect.ID
+		if inspect.Descriptor != nil {
+			digest = inspect.Descriptor.Digest.String()
+		}
+		rows = append(rows, []string{
+			formatPlatform(ocispec.Platform{OS: inspect.Os, Architecture: inspect.Architecture, Variant: inspect.Variant}),
+			shortDigest(digest),
+			string(image.ManifestKindImage),
+			strconv.FormatBool(true),
+			strconv.FormatInt(inspect.Size, 10),
+			"",
+			"",
+		})
+	}
+
+	for _, row := range rows {
+		retval.Data = append(retval.Data, row)
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval, nil
+}
+
+func ImagePull(ref string, auth registry.AuthConfig) (string, error) {
+	logger.Trace(ref)
+
+	docker, err := newClient()
+	if err != nil {
+		return "", err
+	}
+	defer docker.Close()
+
+	encoded, err := registry.EncodeAuthConfig(auth)
+	if err != nil {
+		return "", err
+	}
+
+	reader, err := docker.ImagePull(context.Background(), ref, image.PullOptions{RegistryAuth: encoded})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	// the pull only completes once the progress stream has been drained
+	decoder := json.NewDecoder(reader)
+	var status string
+	for {
+		var msg struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return status, err
+		}
+		if msg.Error != "" {
+			return status, errors.New(msg.Error)
+		}
+		status = msg.Status
+	}
+
+	logger.Info("Pulled: ", ref, " - ", status)
+	return status, nil
+}
+
 func ImageDelete(id string) (string, error) {
 	logger.Trace(id)
 
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		return "", err
 	}
@@ -102,7 +320,7 @@ func ImageHistory(id string) (Results, error) {
 		[]int{0, 0, 0, 0, 0},
 	}
 
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		return retval, err
 	}
@@ -118,7 +336,6 @@ func ImageHistory(id string) (Results, error) {
 	for _, layer := range response {
 		if strings.HasPrefix(layer.ID, shaPrefix) {
 			layer.ID = strings.TrimPrefix(layer.ID, shaPrefix)
-			layer.ID = layer.ID[0:8]
 		} else if layer.ID == imageMissing {
 			layer.ID = ""
 		}
@@ -145,7 +362,7 @@ func ImageHistory(id string) (Results, error) {
 func ImagesPrune(id string) (string, error) {
 	logger.Trace(id)
 
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		return "", err
 	}
@@ -165,44 +382,26 @@ func ImagesPrune(id string) (string, error) {
 	return retval, nil
 }
 
-func ImageInspect(id string) (Results, error) {
-	retval := Results{
-		[]string{"Name", "Value"},
-		[][]string{},
-		[]int{0, 0},
-	}
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+// ImageInspect returns everything the engine knows of the image.
+func ImageInspect(id string) (Inspection, error) {
+	docker, err := newClient()
 	if err != nil {
-		return retval, err
+		return Inspection{}, err
 	}
 	defer docker.Close()
 
-	inspect, err := docker.ImageInspect(context.Background(), id)
+	var raw bytes.Buffer
+	inspect, err := docker.ImageInspect(context.Background(), id, client.ImageInspectWithRawResponse(&raw))
 	if err != nil {
-		return retval, err
+		return Inspection{}, err
 	}
 
-	rows := printObject(inspect, 0)
-	for _, row := range rows {
-		retval.Data = append(retval.Data, row)
-		for i, val := range row {
-			if len(val) > retval.Width[i] {
-				retval.Width[i] = len(val)
-			}
-		}
-	}
-
-	//  log.Printf("inspect.Mounts:[%v]", inspect.Mounts)
-	//  log.Printf("inspect.Config:[%v]", inspect.Config)
-	//  log.Printf("inspect.NetworkSettings:[%v]", inspect.NetworkSettings)
-	//  log.Printf("inspect.ImageManifestDescriptor:[%v]", inspect.ImageManifestDescriptor)
-
-	return retval, nil
+	return Inspection{Tree: InspectTree(inspect), Raw: raw.Bytes()}, nil
 }
 
 func ImageSave(id string) (string, error) {
 
-	docker, err := client.NewClientWithOpts(client.FromEnv)
+	docker, err := newClient()
 	if err != nil {
 		return "", err
 	}
@@ -213,6 +412,9 @@ func ImageSave(id string) (string, error) {
 		return "", err
 	}
 
+	if len(inspect.RepoTags) == 0 {
+		return "", errors.New("image has no tag to name the tarball after: [" + id + "]")
+	}
 	name := inspect.RepoTags[0]
 	base := filepath.Base(name)
 	tarball := strings.Replace(base, ":", "-", -1) + ".tgz"
@@ -0,0 +1,230 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DocumentJSON = "json"
+	DocumentYAML = "yaml"
+)
+
+// Inspection is an object as the engine describes it, as a tree and as
+// the JSON it sent.
+type Inspection struct {
+	Tree []InspectNode
+	Raw  []byte
+}
+
+// Document renders the JSON sent by the engine indented, or as YAML.
+func (i Inspection) Document(format string) ([]byte, error) {
+	if format == DocumentYAML {
+		return ToYAML(json.RawMessage(i.Raw))
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, i.Raw, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// InspectNode is a field of an inspected object, with the fields, keys or
+// elements under it when it has any.
+type InspectNode struct {
+	Name string
+	// Path leads from the object to the node, e.g.
+	// HostConfig.PortBindings["80/tcp"][0].HostPort
+	Path     string
+	Value    string
+	Children []InspectNode
+}
+
+// InspectRow is a node shown in the tree, Depth levels down.
+type InspectRow struct {
+	Node  InspectNode
+	Depth int
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// InspectTree turns the fields of obj into nodes.
+func InspectTree(obj any) []InspectNode {
+	return fieldNodes(reflect.ValueOf(obj), "")
+}
+
+// fieldNodes lists the exported fields of the struct v points at, the
+// fields of embedded structs standing in for them as they do in JSON.
+func fieldNodes(v reflect.Value, path string) []InspectNode {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var retval []InspectNode
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			// as in JSON, an embedded struct of an unexported type still
+			// counts, not one it points at
+			if field.IsExported() || field.Type.Kind() == reflect.Struct {
+				retval = append(retval, fieldNodes(v.Field(i), path)...)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		retval = append(retval, inspectNode(field.Name, joinPath(path, field.Name), v.Field(i)))
+	}
+	return retval
+}
+
+func inspectNode(name string, path string, v reflect.Value) InspectNode {
+	node := InspectNode{Name: name, Path: path}
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			node.Value = "null"
+			return node
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == timeType:
+		if t := v.Interface().(time.Time); !t.IsZero() {
+			node.Value = t.Format(time.RFC3339Nano)
+		}
+		return node
+	case v.Type() == durationType:
+		node.Value = time.Duration(v.Int()).String()
+		return node
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		node.Children = fieldNodes(v, path)
+		node.Value = fmt.Sprintf("{%d}", len(node.Children))
+	case reflect.Map:
+		if v.IsNil() {
+			node.Value = "null"
+			return node
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			label := strconv.Quote(fmt.Sprint(key.Interface()))
+			node.Children = append(node.Children, inspectNode(label, path+"["+label+"]", v.MapIndex(key)))
+		}
+		node.Value = fmt.Sprintf("{%d}", len(node.Children))
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			node.Value = "null"
+			return node
+		}
+		for i := 0; i < v.Len(); i++ {
+			label := "[" + strconv.Itoa(i) + "]"
+			node.Children = append(node.Children, inspectNode(label, path+label, v.Index(i)))
+		}
+		node.Value = fmt.Sprintf("[%d]", len(node.Children))
+	case reflect.String:
+		node.Value = v.String()
+	case reflect.Bool:
+		node.Value = strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		node.Value = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		node.Value = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		node.Value = strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		node.Value = fmt.Sprint(v.Interface())
+	}
+	return node
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// FlattenInspect lists the nodes shown when those whose path is in
+// expanded are open.
+func FlattenInspect(nodes []InspectNode, expanded map[string]bool) []InspectRow {
+	var retval []InspectRow
+	var walk func(nodes []InspectNode, depth int)
+	walk = func(nodes []InspectNode, depth int) {
+		for _, node := range nodes {
+			retval = append(retval, InspectRow{node, depth})
+			if expanded[node.Path] {
+				walk(node.Children, depth+1)
+			}
+		}
+	}
+	walk(nodes, 0)
+	return retval
+}
+
+// BranchPaths lists the paths of every node with children under nodes.
+func BranchPaths(nodes []InspectNode) []string {
+	var retval []string
+	for _, node := range nodes {
+		if len(node.Children) > 0 {
+			retval = append(retval, node.Path)
+			retval = append(retval, BranchPaths(node.Children)...)
+		}
+	}
+	return retval
+}
+
+// InspectResults draws the rows as a tree, the path first so the rows
+// keep their identity as nodes open and close.
+func InspectResults(rows []InspectRow, expanded map[string]bool) Results {
+	retval := Results{
+		[]string{"Path", "Name", "Value"},
+		[][]string{},
+		[]int{0, 0, 0},
+	}
+
+	for _, r := range rows {
+		marker := "  "
+		if len(r.Node.Children) > 0 {
+			marker = "▸ "
+			if expanded[r.Node.Path] {
+				marker = "▾ "
+			}
+		}
+		row := []string{r.Node.Path, strings.Repeat("  ", r.Depth) + marker + r.Node.Name, r.Node.Value}
+		retval.Data = append(retval.Data, row)
+
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval
+}
@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type inspectBase struct {
+	ID      string
+	Created time.Time
+}
+
+type inspectState struct {
+	Running bool
+	Pid     int
+}
+
+type inspectSample struct {
+	inspectBase
+	*inspectState
+	Name    string
+	Ports   map[string][]uint16
+	Sizes   map[int]float64
+	Labels  map[string]string
+	Config  *struct{ Image string }
+	Extra   any
+	Env     []string
+	Args    []string
+	Flags   [2]bool
+	Timeout time.Duration
+	Started time.Time
+	Nested  struct {
+		Deep *inspectState
+	}
+	hidden string
+}
+
+// inspectLines gives the path and value of every row, every node open.
+func inspectLines(nodes []InspectNode) []string {
+	expanded := make(map[string]bool)
+	for _, path := range BranchPaths(nodes) {
+		expanded[path] = true
+	}
+
+	var retval []string
+	for _, row := range FlattenInspect(nodes, expanded) {
+		retval = append(retval, row.Node.Path+" = "+row.Node.Value)
+	}
+	return retval
+}
+
+func TestInspectTree(t *testing.T) {
+	created := time.Date(2024, 5, 6, 7, 8, 9, 500, time.UTC)
+	sample := inspectSample{
+		inspectBase:  inspectBase{ID: "abc", Created: created},
+		inspectState: &inspectState{Running: true, Pid: 42},
+		Name:         "web",
+		Ports:        map[string][]uint16{"80/tcp": {8080}, "443/tcp": {8443, 9443}},
+		Sizes:        map[int]float64{10: 1.5, 9: 0.25},
+		Extra:        map[string]any{"k": nil},
+		Env:          []string{},
+		Timeout:      90 * time.Second,
+		hidden:       "not shown",
+	}
+
+	// embedded structs stand in for their fields, unless pointed at with an
+	// unexported type, map keys are sorted and quoted, nil pointers, maps
+	// and slices are null, a zero time is empty
+	want := []string{
+		"ID = abc",
+		"Created = 2024-05-06T07:08:09.0000005Z",
+		"Name = web",
+		"Ports = {2}",
+		`Ports["443/tcp"] = [2]`,
+		`Ports["443/tcp"][0] = 8443`,
+		`Ports["443/tcp"][1] = 9443`,
+		`Ports["80/tcp"] = [1]`,
+		`Ports["80/tcp"][0] = 8080`,
+		"Sizes = {2}",
+		`Sizes["10"] = 1.5`,
+		`Sizes["9"] = 0.25`,
+		"Labels = null",
+		"Config = null",
+		"Extra = {1}",
+		`Extra["k"] = null`,
+		"Env = [0]",
+		"Args = null",
+		"Flags = [2]",
+		"Flags[0] = false",
+		"Flags[1] = false",
+		"Timeout = 1m30s",
+		"Started = ",
+		"Nested = {1}",
+		"Nested.Deep = null",
+	}
+
+	if got := inspectLines(InspectTree(&sample)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+
+	// a pointer that is set is followed
+	sample.Nested.Deep = &inspectState{Running: true, Pid: 7}
+	got := inspectLines(InspectTree(sample))
+	if want := []string{"Nested.Deep = {2}", "Nested.Deep.Running = true", "Nested.Deep.Pid = 7"}; !reflect.DeepEqual(got[len(got)-3:], want) {
+		t.Errorf("got %q, want %q", got[len(got)-3:], want)
+	}
+}
+
+func TestInspectTreeNotStruct(t *testing.T) {
+	var nothing *inspectSample
+	for _, obj := range []any{nil, nothing, "text", 42, []int{1}} {
+		if got := InspectTree(obj); got != nil {
+			t.Errorf("InspectTree(%#v) = %v, want nothing", obj, got)
+		}
+	}
+}
+
+func TestFlattenInspect(t *testing.T) {
+	nodes := InspectTree(struct {
+		A struct{ B struct{ C int } }
+		D int
+	}{})
+
+	tests := []struct {
+		name     string
+		expanded map[string]bool
+		want     []string
+		depths   []int
+	}{
+		{"closed", nil, []string{"A", "D"}, []int{0, 0}},
+		{"open", map[string]bool{"A": true}, []string{"A", "A.B", "D"}, []int{0, 1, 0}},
+		{"all open", map[string]bool{"A": true, "A.B": true}, []string{"A", "A.B", "A.B.C", "D"}, []int{0, 1, 2, 0}},
+		// a node under a closed one stays hidden even when open itself
+		{"under closed", map[string]bool{"A.B": true}, []string{"A", "D"}, []int{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			var depths []int
+			for _, row := range FlattenInspect(nodes, tt.expanded) {
+				paths = append(paths, row.Node.Path)
+				depths = append(depths, row.Depth)
+			}
+			if !reflect.DeepEqual(paths, tt.want) || !reflect.DeepEqual(depths, tt.depths) {
+				t.Errorf("got %v %v, want %v %v", paths, depths, tt.want, tt.depths)
+			}
+		})
+	}
+}
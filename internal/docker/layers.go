@@ -0,0 +1,511 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/presselam/yadc/internal/logger"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type FileStatus string
+
+const (
+	FileAdded    FileStatus = "Added"
+	FileModified FileStatus = "Modified"
+	FileRemoved  FileStatus = "Removed"
+
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+	maxMetadata    = 16 << 20
+	LayerTotal     = "*"
+)
+
+type LayerFile struct {
+	Path   string
+	Size   int64
+	Mode   fs.FileMode
+	Status FileStatus
+}
+
+type Layer struct {
+	Index   int
+	Digest  string
+	Command string
+	Size    int64
+	Wasted  int64
+	Files   []LayerFile
+}
+
+type WastedFile struct {
+	Path  string
+	Count int
+	Size  int64
+}
+
+type ImageAnalysis struct {
+	ID         string
+	Layers     []Layer
+	Size       int64
+	Wasted     int64
+	Efficiency float64
+	Waste      []WastedFile
+}
+
+type saveManifest struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+type imageConfig struct {
+	History []struct {
+		CreatedBy  string `json:"created_by"`
+		EmptyLayer bool   `json:"empty_layer"`
+	} `json:"history"`
+}
+
+type layerEntry struct {
+	path string
+	size int64
+	mode fs.FileMode
+}
+
+// ImageLayers reads the image archive produced by the save API and builds
+// the file tree of every layer, marking what each layer adds, modifies and
+// removes along with the space wasted by files that later layers overwrite
+// or delete.
+func ImageLayers(id string) (ImageAnalysis, error) {
+	logger.Trace(id)
+	retval := ImageAnalysis{ID: id}
+
+	docker, err := newClient()
+	if err != nil {
+		return retval, err
+	}
+	defer docker.Close()
+
+	data, err := docker.ImageSave(context.Background(), []string{id})
+	if err != nil {
+		return retval, err
+	}
+	defer data.Close()
+
+	return analyzeArchive(id, data)
+}
+
+// savedImage is what walkArchive gets out of an archive written by the
+// save API.
+type savedImage struct {
+	manifest saveManifest
+	// layers names the layer blobs from the base up, as they were handed
+	// to the layer reader
+	layers []string
+	config []byte
+}
+
+// walkArchive goes through an archive written by the save API, handing
+// every layer blob to layer as it comes by. The blobs may come in any
+// order and be symlinked from their legacy paths, the layers returned
+// are in order with their links resolved. Every layer in the manifest has
+// to be there.
+func walkArchive(archive io.Reader, layer func(name string, r io.Reader) error) (savedImage, error) {
+	var retval savedImage
+
+	metadata := make(map[string][]byte)
+	links := make(map[string]string)
+	read := make(map[string]bool)
+
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return retval, err
+		}
+
+		name := path.Clean(hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			links[name] = path.Join(path.Dir(name), hdr.Linkname)
+			continue
+		case tar.TypeReg:
+		default:
+			continue
+		}
+
+		br := bufio.NewReaderSize(tr, 1024)
+		head, _ := br.Peek(512)
+		switch {
+		case isArchive(head):
+			if err := layer(name, br); err != nil {
+				return retval, err
+			}
+			read[name] = true
+		case hdr.Size < maxMetadata:
+			buf, err := io.ReadAll(br)
+			if err != nil {
+				return retval, err
+			}
+			metadata[name] = buf
+		}
+	}
+
+	var manifests []saveManifest
+	if err := json.Unmarshal(metadata["manifest.json"], &manifests); err != nil {
+		return retval, err
+	}
+	if len(manifests) == 0 {
+		return retval, errors.New("image archive has no manifest")
+	}
+	retval.manifest = manifests[0]
+
+	for _, name := range retval.manifest.Layers {
+		blob := resolve(links, path.Clean(name))
+		// a cut short archive can still hold the manifest
+		if !read[blob] {
+			return retval, errors.New("image archive is missing layer: [" + name + "]")
+		}
+		retval.layers = append(retval.layers, blob)
+	}
+	retval.config = metadata[resolve(links, path.Clean(retval.manifest.Config))]
+
+	return retval, nil
+}
+
+func analyzeArchive(id string, archive io.Reader) (ImageAnalysis, error) {
+	retval := ImageAnalysis{ID: id}
+
+	blobs := make(map[string][]layerEntry)
+	saved, err := walkArchive(archive, func(name string, r io.Reader) error {
+		entries, err := readLayer(r)
+		blobs[name] = entries
+		return err
+	})
+	if err != nil {
+		return retval, err
+	}
+
+	var config imageConfig
+	if saved.config != nil {
+		if err := json.Unmarshal(saved.config, &config); err != nil {
+			logger.Warn("docker.layers.config:", err)
+		}
+	}
+
+	var commands []string
+	for _, h := range config.History {
+		if !h.EmptyLayer {
+			commands = append(commands, h.CreatedBy)
+		}
+	}
+
+	var ordered [][]layerEntry
+	for _, name := range saved.layers {
+		ordered = append(ordered, blobs[name])
+	}
+
+	retval.Layers, retval.Waste = buildLayers(ordered)
+	for i := range retval.Layers {
+		retval.Layers[i].Digest = layerDigest(saved.manifest.Layers[i])
+		if i < len(commands) {
+			retval.Layers[i].Command = commands[i]
+		}
+		retval.Size += retval.Layers[i].Size
+		retval.Wasted += retval.Layers[i].Wasted
+	}
+
+	retval.Efficiency = 1
+	if retval.Size > 0 {
+		retval.Efficiency = 1 - float64(retval.Wasted)/float64(retval.Size)
+	}
+
+	return retval, nil
+}
+
+// buildLayers replays the layers in order against a merged view of the
+// filesystem to work out the status of every entry and which bytes never
+// make it to the final image.
+func buildLayers(layers [][]layerEntry) ([]Layer, []WastedFile) {
+	var retval []Layer
+
+	// merged view: path => the layer holding the live version
+	type version struct {
+		layer int
+		size  int64
+		dir   bool
+	}
+	merged := make(map[string]version)
+	shadowed := make(map[string]*WastedFile)
+
+	waste := func(p string, v version) int64 {
+		if v.dir {
+			return 0
+		}
+		retval[v.layer].Wasted += v.size
+		w, ok := shadowed[p]
+		if !ok {
+			w = &WastedFile{Path: p}
+			shadowed[p] = w
+		}
+		w.Count++
+		w.Size += v.size
+		return v.size
+	}
+
+	for idx, entries := range layers {
+		retval = append(retval, Layer{Index: idx})
+
+		// whiteouts apply to the lower layers, so handle them first
+		removed := make(map[string]int64)
+		var files []layerEntry
+		for _, e := range entries {
+			dir, base := path.Split(e.path)
+			dir = path.Clean(dir)
+			switch {
+			case base == whiteoutOpaque:
+				for p, v := range merged {
+					if strings.HasPrefix(p, dir+"/") {
+						removed[p] += waste(p, v)
+						delete(merged, p)
+					}
+				}
+			case strings.HasPrefix(base, whiteoutPrefix):
+				target := path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))
+				for p, v := range merged {
+					if p == target || strings.HasPrefix(p, target+"/") {
+						removed[target] += waste(p, v)
+						delete(merged, p)
+					}
+				}
+			default:
+				files = append(files, e)
+			}
+		}
+
+		for _, e := range files {
+			status := FileAdded
+			if v, ok := merged[e.path]; ok {
+				status = FileModified
+				waste(e.path, v)
+			}
+			if _, ok := removed[e.path]; ok {
+				status = FileModified
+				delete(removed, e.path)
+			}
+
+			retval[idx].Files = append(retval[idx].Files, LayerFile{
+				Path:   e.path,
+				Size:   e.size,
+				Mode:   e.mode,
+				Status: status,
+			})
+			retval[idx].Size += e.size
+			merged[e.path] = version{idx, e.size, e.mode.IsDir()}
+		}
+
+		for p, size := range removed {
+			retval[idx].Files = append(retval[idx].Files, LayerFile{
+				Path:   p,
+				Size:   size,
+				Status: FileRemoved,
+			})
+		}
+
+		sort.Slice(retval[idx].Files, func(i, j int) bool {
+			return retval[idx].Files[i].Path < retval[idx].Files[j].Path
+		})
+	}
+
+	var wasted []WastedFile
+	for _, w := range shadowed {
+		if w.Size > 0 {
+			wasted = append(wasted, *w)
+		}
+	}
+	sort.Slice(wasted, func(i, j int) bool {
+		if wasted[i].Size == wasted[j].Size {
+			return wasted[i].Path < wasted[j].Path
+		}
+		return wasted[i].Size > wasted[j].Size
+	})
+
+	return retval, wasted
+}
+
+func readLayer(r io.Reader) ([]layerEntry, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(2); bytes.Equal(head, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	var retval []layerEntry
+	tr := tar.NewReader(br)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return retval, err
+		}
+
+		name := path.Clean("/" + hdr.Name)
+		if name == "/" {
+			continue
+		}
+		retval = append(retval, layerEntry{
+			path: name,
+			size: hdr.Size,
+			mode: hdr.FileInfo().Mode(),
+		})
+	}
+
+	return retval, nil
+}
+
+func isArchive(head []byte) bool {
+	if len(head) >= 2 && head[0] == 0x1f && head[1] == 0x8b {
+		return true
+	}
+	if len(head) < 512 {
+		return false
+	}
+	if string(head[257:262]) == "ustar" {
+		return true
+	}
+	// an empty layer is nothing but zeroed end-of-archive blocks
+	return bytes.Count(head, []byte{0}) == len(head)
+}
+
+func resolve(links map[string]string, name string) string {
+	for i := 0; i < 8; i++ {
+		target, ok := links[name]
+		if !ok {
+			break
+		}
+		name = target
+	}
+	return name
+}
+
+func layerDigest(name string) string {
+	name = path.Clean(name)
+	if strings.HasPrefix(name, "blobs/sha256/") {
+		return strings.TrimPrefix(name, "blobs/sha256/")
+	}
+	return path.Base(path.Dir(name))
+}
+
+func (a ImageAnalysis) LayerResults() Results {
+	retval := Results{
+		[]string{"Layer", "Digest", "Size", "Wasted", "Created By"},
+		[][]string{},
+		[]int{0, 0, 0, 0, 0},
+	}
+
+	summary := fmt.Sprintf("efficiency: %.2f%%  wasted files: %d", 100*a.Efficiency, len(a.Waste))
+	rows := [][]string{
+		{LayerTotal, "", strconv.FormatInt(a.Size, 10), strconv.FormatInt(a.Wasted, 10), summary},
+	}
+	for _, layer := range a.Layers {
+		digest := layer.Digest
+		if len(digest) > 12 {
+			digest = digest[0:12]
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(layer.Index),
+			digest,
+			strconv.FormatInt(layer.Size, 10),
+			strconv.FormatInt(layer.Wasted, 10),
+			layer.Command,
+		})
+	}
+
+	for _, row := range rows {
+		retval.Data = append(retval.Data, row)
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval
+}
+
+func (a ImageAnalysis) FileResults(index int) Results {
+	retval := Results{
+		[]string{"Status", "Size", "Path"},
+		[][]string{},
+		[]int{0, 0, 0},
+	}
+	if index < 0 || index >= len(a.Layers) {
+		return retval
+	}
+
+	for _, f := range a.Layers[index].Files {
+		depth := strings.Count(f.Path, "/") - 1
+		name := path.Base(f.Path)
+		if f.Mode.IsDir() {
+			name += "/"
+		}
+
+		row := []string{
+			string(f.Status),
+			strconv.FormatInt(f.Size, 10),
+			strings.Repeat("  ", depth) + name,
+		}
+		retval.Data = append(retval.Data, row)
+
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval
+}
+
+func (a ImageAnalysis) WasteResults() Results {
+	retval := Results{
+		[]string{"Count", "Wasted", "Path"},
+		[][]string{},
+		[]int{0, 0, 0},
+	}
+
+	for _, w := range a.Waste {
+		row := []string{
+			strconv.Itoa(w.Count),
+			strconv.FormatInt(w.Size, 10),
+			w.Path,
+		}
+		retval.Data = append(retval.Data, row)
+
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval
+}
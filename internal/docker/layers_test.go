@@ -0,0 +1,264 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func file(p string, size int64) layerEntry {
+	return layerEntry{path: p, size: size, mode: 0644}
+}
+
+func dir(p string) layerEntry {
+	return layerEntry{path: p, mode: fs.ModeDir | 0755}
+}
+
+func TestBuildLayers(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers [][]layerEntry
+		status []map[string]FileStatus
+		wasted []int64
+		waste  []WastedFile
+	}{
+		{
+			name: "added",
+			layers: [][]layerEntry{
+				{dir("/etc"), file("/etc/hosts", 10)},
+			},
+			status: []map[string]FileStatus{
+				{"/etc": FileAdded, "/etc/hosts": FileAdded},
+			},
+			wasted: []int64{0},
+		},
+		{
+			name: "overwritten",
+			layers: [][]layerEntry{
+				{dir("/etc"), file("/etc/hosts", 10)},
+				{dir("/etc"), file("/etc/hosts", 20)},
+			},
+			status: []map[string]FileStatus{
+				{"/etc": FileAdded, "/etc/hosts": FileAdded},
+				{"/etc": FileModified, "/etc/hosts": FileModified},
+			},
+			// directories are not waste
+			wasted: []int64{10, 0},
+			waste:  []WastedFile{{Path: "/etc/hosts", Count: 1, Size: 10}},
+		},
+		{
+			name: "whiteout",
+			layers: [][]layerEntry{
+				{dir("/tmp"), file("/tmp/cache", 100), dir("/tmp/sub"), file("/tmp/sub/a", 5)},
+				{file("/tmp/.wh.cache", 0), file("/tmp/.wh.sub", 0)},
+			},
+			status: []map[string]FileStatus{
+				{"/tmp": FileAdded, "/tmp/cache": FileAdded, "/tmp/sub": FileAdded, "/tmp/sub/a": FileAdded},
+				{"/tmp/cache": FileRemoved, "/tmp/sub": FileRemoved},
+			},
+			wasted: []int64{105, 0},
+			waste: []WastedFile{
+				{Path: "/tmp/cache", Count: 1, Size: 100},
+				{Path: "/tmp/sub/a", Count: 1, Size: 5},
+			},
+		},
+		{
+			name: "opaque",
+			layers: [][]layerEntry{
+				{dir("/var"), file("/var/a", 3), file("/var/b", 4), file("/varnish", 7)},
+				{dir("/var"), file("/var/.wh..wh..opq", 0), file("/var/a", 1)},
+			},
+			status: []map[string]FileStatus{
+				{"/var": FileAdded, "/var/a": FileAdded, "/var/b": FileAdded, "/varnish": FileAdded},
+				// put back after the whiteout, a is modified rather than removed
+				{"/var": FileModified, "/var/a": FileModified, "/var/b": FileRemoved},
+			},
+			wasted: []int64{7, 0},
+			waste: []WastedFile{
+				{Path: "/var/b", Count: 1, Size: 4},
+				{Path: "/var/a", Count: 1, Size: 3},
+			},
+		},
+		{
+			name: "overwritten twice",
+			layers: [][]layerEntry{
+				{file("/bin/app", 50)},
+				{file("/bin/app", 60)},
+				{file("/bin/app", 70)},
+			},
+			status: []map[string]FileStatus{
+				{"/bin/app": FileAdded},
+				{"/bin/app": FileModified},
+				{"/bin/app": FileModified},
+			},
+			wasted: []int64{50, 60, 0},
+			waste:  []WastedFile{{Path: "/bin/app", Count: 2, Size: 110}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layers, waste := buildLayers(tt.layers)
+			if len(layers) != len(tt.layers) {
+				t.Fatalf("got %d layers, want %d", len(layers), len(tt.layers))
+			}
+
+			for i, layer := range layers {
+				status := make(map[string]FileStatus)
+				for _, f := range layer.Files {
+					status[f.Path] = f.Status
+				}
+				if !reflect.DeepEqual(status, tt.status[i]) {
+					t.Errorf("layer %d: status %v, want %v", i, status, tt.status[i])
+				}
+				if layer.Wasted != tt.wasted[i] {
+					t.Errorf("layer %d: wasted %d, want %d", i, layer.Wasted, tt.wasted[i])
+				}
+			}
+
+			if !reflect.DeepEqual(waste, tt.waste) {
+				t.Errorf("waste %v, want %v", waste, tt.waste)
+			}
+		})
+	}
+}
+
+func TestBuildLayersRemovedSize(t *testing.T) {
+	layers, _ := buildLayers([][]layerEntry{
+		{dir("/opt"), file("/opt/x", 8), file("/opt/y", 2)},
+		{file("/.wh.opt", 0)},
+	})
+
+	files := layers[1].Files
+	if len(files) != 1 || files[0].Path != "/opt" || files[0].Size != 10 {
+		t.Errorf("removed %v, want /opt with the 10 bytes under it", files)
+	}
+}
+
+type tarFile struct {
+	name string
+	body string
+	link string
+	mode int64
+}
+
+// tarball builds a tar archive of files, gzipped when zip is set.
+func tarball(t *testing.T, zip bool, files ...tarFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if zip {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.body)), Typeflag: tar.TypeReg}
+		if f.mode != 0 {
+			hdr.Mode = f.mode
+		}
+		if f.link != "" {
+			hdr = &tar.Header{Name: f.name, Linkname: f.link, Mode: 0777, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// savedArchive lays out an image the way the save API does: the layers as
+// blobs, the top one linked from its legacy layer directory, the config and
+// the manifest listing the layers from the base up.
+func savedArchive(t *testing.T, base []byte, top []byte) []byte {
+	t.Helper()
+	config := `{"history":[{"created_by":"ADD rootfs /"},{"created_by":"ENV A=1","empty_layer":true},{"created_by":"RUN apt-get install"}]}`
+	manifest := `[{"Config":"blobs/sha256/cfg","RepoTags":["demo:1"],"Layers":["blobs/sha256/aaaa","bbbb/layer.tar"]}]`
+
+	// the manifest coming first and the layers out of order should not matter
+	return tarball(t, false,
+		tarFile{name: "manifest.json", body: manifest},
+		tarFile{name: "bbbb/layer.tar", link: "../blobs/sha256/bbbb"},
+		tarFile{name: "blobs/sha256/bbbb", body: string(top)},
+		tarFile{name: "blobs/sha256/aaaa", body: string(base)},
+		tarFile{name: "blobs/sha256/cfg", body: config},
+	)
+}
+
+func TestAnalyzeArchive(t *testing.T) {
+	base := tarball(t, true,
+		tarFile{name: "etc/hosts", body: "127.0.0.1 localhost\n"},
+		tarFile{name: "tmp/cache", body: strings.Repeat("x", 100)},
+	)
+	top := tarball(t, false,
+		tarFile{name: "etc/hosts", body: "::1 localhost\n"},
+		tarFile{name: "tmp/.wh.cache"},
+	)
+
+	analysis, err := analyzeArchive("demo", bytes.NewReader(savedArchive(t, base, top)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(analysis.Layers) != 2 {
+		t.Fatalf("got %d layers, want 2", len(analysis.Layers))
+	}
+	for i, want := range []struct {
+		digest  string
+		command string
+	}{
+		{"aaaa", "ADD rootfs /"},
+		{"bbbb", "RUN apt-get install"},
+	} {
+		layer := analysis.Layers[i]
+		if layer.Digest != want.digest || layer.Command != want.command {
+			t.Errorf("layer %d: %s %q, want %s %q", i, layer.Digest, layer.Command, want.digest, want.command)
+		}
+	}
+	if analysis.Wasted != 120 {
+		t.Errorf("wasted %d, want the old hosts and the cache", analysis.Wasted)
+	}
+}
+
+func TestAnalyzeArchiveInvalid(t *testing.T) {
+	layer := tarball(t, false, tarFile{name: "a", body: "a"})
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{"no manifest", tarball(t, false, tarFile{name: "blobs/sha256/aaaa", body: string(layer)})},
+		{"empty manifest", tarball(t, false, tarFile{name: "manifest.json", body: "[]"})},
+		{"broken manifest", tarball(t, false, tarFile{name: "manifest.json", body: "[{"})},
+		{"truncated", savedArchive(t, layer, layer)[:1000]},
+		{"not a tar", []byte("definitely not an image archive")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := analyzeArchive("bad", bytes.NewReader(tt.archive)); err == nil {
+				t.Error("no error")
+			}
+			if _, err := scanArchive("bad", bytes.NewReader(tt.archive)); err == nil {
+				t.Error("no error from the package scan")
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package docker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/presselam/yadc/internal/logger"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	LogFormatPlain = "plain"
+	LogFormatGzip  = "gzip"
+
+	progressInterval = 250 * time.Millisecond
+	// files are written under this suffix and renamed once complete
+	partialSuffix = ".partial"
+)
+
+// LogExportSpec holds the settings of a log export. Since and Until take
+// the same values as docker logs, Streams is "stdout", "stderr" or both.
+type LogExportSpec struct {
+	Since      string
+	Until      string
+	Streams    string
+	Timestamps string
+	Format     string
+	Path       string
+}
+
+// Validate checks the spec without talking to the engine.
+func (spec LogExportSpec) Validate() error {
+	_, err := spec.options()
+	if err != nil {
+		return err
+	}
+	if spec.Path == "" {
+		return errors.New("path is required")
+	}
+	switch spec.Format {
+	case "", LogFormatPlain, LogFormatGzip:
+	default:
+		return errors.New("invalid format: [" + spec.Format + "]")
+	}
+	return nil
+}
+
+func (spec LogExportSpec) options() (container.LogsOptions, error) {
+	retval := container.LogsOptions{Since: spec.Since, Until: spec.Until}
+
+	streams := strings.FieldsFunc(strings.ToLower(spec.Streams), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '+'
+	})
+	if len(streams) == 0 {
+		streams = []string{"stdout", "stderr"}
+	}
+	for _, stream := range streams {
+		switch stream {
+		case "stdout":
+			retval.ShowStdout = true
+		case "stderr":
+			retval.ShowStderr = true
+		default:
+			return retval, errors.New("invalid stream: [" + stream + "]")
+		}
+	}
+
+	switch strings.ToLower(spec.Timestamps) {
+	case "", "n", "no", "false":
+	case "y", "yes", "true":
+		retval.Timestamps = true
+	default:
+		return retval, errors.New("invalid timestamps: [" + spec.Timestamps + "]")
+	}
+
+	return retval, nil
+}
+
+// progressWriter counts the bytes going through and reports them at most
+// every progressInterval.
+type progressWriter struct {
+	w        io.Writer
+	written  int64
+	last     time.Time
+	progress func(int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.progress != nil && time.Since(p.last) >= progressInterval {
+		p.last = time.Now()
+		p.progress(p.written)
+	}
+	return n, err
+}
+
+// ContainerLogExport streams the logs of a container straight from the
+// engine into a file, so the size of the logs does not matter. The file
+// only shows up once complete. It returns the number of log bytes written.
+func ContainerLogExport(id string, spec LogExportSpec, progress func(int64)) (int64, error) {
+	logger.Trace(id, spec.Path)
+
+	options, err := spec.options()
+	if err != nil {
+		return 0, err
+	}
+
+	docker, err := newClient()
+	if err != nil {
+		return 0, err
+	}
+	defer docker.Close()
+
+	inspect, err := docker.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return 0, err
+	}
+
+	reader, err := docker.ContainerLogs(context.Background(), id, options)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	partial := spec.Path + partialSuffix
+	f, err := os.Create(partial)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(partial)
+	defer f.Close()
+
+	buffered := bufio.NewWriter(f)
+	var out io.Writer = buffered
+	var gz *gzip.Writer
+	if spec.Format == LogFormatGzip {
+		gz = gzip.NewWriter(buffered)
+		out = gz
+	}
+
+	counter := &progressWriter{w: out, progress: progress}
+	if inspect.Config.Tty {
+		_, err = io.Copy(counter, reader)
+	} else {
+		_, err = stdcopy.StdCopy(counter, counter, reader)
+	}
+	if err != nil {
+		return counter.written, err
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return counter.written, err
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return counter.written, err
+	}
+	if err := f.Close(); err != nil {
+		return counter.written, err
+	}
+	if err := os.Rename(partial, spec.Path); err != nil {
+		return counter.written, err
+	}
+	if progress != nil {
+		progress(counter.written)
+	}
+
+	logger.Info("Exported logs: ", spec.Path)
+	return counter.written, nil
+}
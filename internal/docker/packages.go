@@ -0,0 +1,333 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+type PackageType string
+
+const (
+	PackageDeb    PackageType = "deb"
+	PackageApk    PackageType = "apk"
+	PackageRPM    PackageType = "rpm"
+	PackageGolang PackageType = "golang"
+	PackageNpm    PackageType = "npm"
+)
+
+// Package is one entry of an image's package inventory.
+type Package struct {
+	Type     PackageType
+	Name     string
+	Version  string
+	Arch     string
+	License  string
+	Source   string
+	Location string
+	PURL     string
+}
+
+// Distro is what os-release says about the image.
+type Distro struct {
+	ID        string
+	VersionID string
+	Name      string
+}
+
+type packageParser func(data []byte) ([]Package, error)
+
+// parserFor picks the parser for the file at p, if it is one of the
+// package databases or lockfiles we know.
+func parserFor(p string) packageParser {
+	dir, base := path.Split(p)
+	switch {
+	case p == "/var/lib/dpkg/status":
+		return func(data []byte) ([]Package, error) { return parseDpkg(data, true) }
+	case dir == "/var/lib/dpkg/status.d/" && !strings.HasSuffix(base, ".md5sums"):
+		// distroless keeps one file per package, without a Status field
+		return func(data []byte) ([]Package, error) { return parseDpkg(data, false) }
+	case p == "/lib/apk/db/installed":
+		return parseApk
+	case base == "rpmdb.sqlite" && isRpmDir(dir):
+		return parseRpmSqlite
+	case base == "Packages.db" && isRpmDir(dir):
+		return parseRpmNdb
+	case base == "package-lock.json" && !strings.Contains(dir, "/node_modules/"):
+		return parseNpmLock
+	}
+	return nil
+}
+
+func isRpmDir(dir string) bool {
+	return dir == "/var/lib/rpm/" || dir == "/usr/lib/sysimage/rpm/"
+}
+
+func isOSRelease(p string) bool {
+	return p == "/etc/os-release" || p == "/usr/lib/os-release"
+}
+
+// paragraphs splits the deb822 style records of dpkg and apk, calling
+// field for every key and value and end after every record.
+func paragraphs(data []byte, sep string, field func(key, value string), end func()) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxMetadata)
+
+	open := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if open {
+				end()
+			}
+			open = false
+			continue
+		}
+		// continuation lines only matter for descriptions
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, sep)
+		if !ok {
+			continue
+		}
+		open = true
+		field(key, strings.TrimSpace(value))
+	}
+	if open {
+		end()
+	}
+}
+
+func parseDpkg(data []byte, installedOnly bool) ([]Package, error) {
+	var retval []Package
+
+	pkg := Package{Type: PackageDeb}
+	status := ""
+	paragraphs(data, ":", func(key, value string) {
+		switch key {
+		case "Package":
+			pkg.Name = value
+		case "Version":
+			pkg.Version = value
+		case "Architecture":
+			pkg.Arch = value
+		case "Source":
+			// the source may carry its own version in brackets
+			pkg.Source, _, _ = strings.Cut(value, " ")
+		case "Status":
+			status = value
+		}
+	}, func() {
+		if pkg.Name != "" && (!installedOnly || strings.HasSuffix(status, " installed")) {
+			retval = append(retval, pkg)
+		}
+		pkg = Package{Type: PackageDeb}
+		status = ""
+	})
+
+	return retval, nil
+}
+
+func parseApk(data []byte) ([]Package, error) {
+	var retval []Package
+
+	pkg := Package{Type: PackageApk}
+	paragraphs(data, ":", func(key, value string) {
+		switch key {
+		case "P":
+			pkg.Name = value
+		case "V":
+			pkg.Version = value
+		case "A":
+			pkg.Arch = value
+		case "L":
+			pkg.License = value
+		case "o":
+			pkg.Source = value
+		}
+	}, func() {
+		if pkg.Name != "" {
+			retval = append(retval, pkg)
+		}
+		pkg = Package{Type: PackageApk}
+	})
+
+	return retval, nil
+}
+
+type npmLock struct {
+	Packages map[string]struct {
+		Version string `json:"version"`
+		License any    `json:"license"`
+		Link    bool   `json:"link"`
+	} `json:"packages"`
+	Dependencies map[string]npmDependency `json:"dependencies"`
+}
+
+type npmDependency struct {
+	Version      string                   `json:"version"`
+	Dependencies map[string]npmDependency `json:"dependencies"`
+}
+
+// parseNpmLock reads the packages map of lockfile v2 and v3, falling back
+// to the nested dependencies of v1.
+func parseNpmLock(data []byte) ([]Package, error) {
+	var lock npmLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	var retval []Package
+	if len(lock.Packages) > 0 {
+		for key, entry := range lock.Packages {
+			idx := strings.LastIndex(key, "node_modules/")
+			if idx < 0 || entry.Link || entry.Version == "" {
+				continue
+			}
+			license, _ := entry.License.(string)
+			retval = append(retval, Package{
+				Type:    PackageNpm,
+				Name:    key[idx+len("node_modules/"):],
+				Version: entry.Version,
+				License: license,
+			})
+		}
+		return retval, nil
+	}
+
+	var walk func(map[string]npmDependency)
+	walk = func(deps map[string]npmDependency) {
+		for name, dep := range deps {
+			if dep.Version != "" {
+				retval = append(retval, Package{Type: PackageNpm, Name: name, Version: dep.Version})
+			}
+			walk(dep.Dependencies)
+		}
+	}
+	walk(lock.Dependencies)
+
+	return retval, nil
+}
+
+// parseGoBinary lists the modules a Go binary was built from, with the
+// toolchain as the stdlib package.
+func parseGoBinary(data []byte) ([]Package, error) {
+	info, err := buildinfo.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	retval := []Package{
+		{Type: PackageGolang, Name: "stdlib", Version: info.GoVersion},
+	}
+	if info.Main.Path != "" {
+		retval = append(retval, Package{Type: PackageGolang, Name: info.Main.Path, Version: info.Main.Version})
+	}
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		retval = append(retval, Package{Type: PackageGolang, Name: dep.Path, Version: dep.Version})
+	}
+
+	return retval, nil
+}
+
+func parseOSRelease(data []byte) Distro {
+	var retval Distro
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			retval.ID = value
+		case "VERSION_ID":
+			retval.VersionID = value
+		case "PRETTY_NAME":
+			retval.Name = value
+		}
+	}
+	return retval
+}
+
+// purl builds the package URL of pkg, see
+// https://github.com/package-url/purl-spec
+func purl(pkg Package, distro Distro) string {
+	var namespace, name string
+	var qualifiers []string
+
+	switch pkg.Type {
+	case PackageDeb, PackageApk, PackageRPM:
+		namespace = distro.ID
+		if namespace == "" {
+			namespace = map[PackageType]string{
+				PackageDeb: "debian",
+				PackageApk: "alpine",
+				PackageRPM: "redhat",
+			}[pkg.Type]
+		}
+		name = purlEscape(pkg.Name)
+
+		version := pkg.Version
+		if pkg.Type == PackageRPM {
+			if epoch, rest, ok := strings.Cut(version, ":"); ok {
+				qualifiers = append(qualifiers, "epoch="+purlEscape(epoch))
+				version = rest
+			}
+		}
+		if pkg.Arch != "" {
+			qualifiers = append(qualifiers, "arch="+purlEscape(pkg.Arch))
+		}
+		if distro.ID != "" {
+			qualifiers = append(qualifiers, "distro="+purlEscape(distro.ID+"-"+distro.VersionID))
+		}
+		sort.Strings(qualifiers)
+
+		retval := "pkg:" + string(pkg.Type) + "/" + purlEscape(namespace) + "/" + name
+		if version != "" {
+			retval += "@" + purlEscape(version)
+		}
+		if len(qualifiers) > 0 {
+			retval += "?" + strings.Join(qualifiers, "&")
+		}
+		return retval
+
+	case PackageGolang, PackageNpm:
+		var segments []string
+		for _, segment := range strings.Split(pkg.Name, "/") {
+			segments = append(segments, purlEscape(segment))
+		}
+		name = strings.Join(segments, "/")
+	}
+
+	retval := "pkg:" + string(pkg.Type) + "/" + name
+	if pkg.Version != "" && pkg.Version != "(devel)" {
+		retval += "@" + purlEscape(pkg.Version)
+	}
+	return retval
+}
+
+func purlEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '.', c == '-', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,278 @@
+package docker
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+func sortPackages(pkgs []Package) []Package {
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Name < pkgs[j].Name
+	})
+	return pkgs
+}
+
+func TestParseDpkg(t *testing.T) {
+	pkgs, err := parseDpkg(readFixture(t, "dpkg/status"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// removed-pkg only has its config files left, and the package named
+	// in the description is no package at all
+	want := []Package{
+		{Type: PackageDeb, Name: "libc6", Version: "2.36-9+deb12u4", Arch: "amd64", Source: "glibc"},
+		{Type: PackageDeb, Name: "libssl3", Version: "3.0.11-1~deb12u2+b1", Arch: "amd64", Source: "openssl"},
+		{Type: PackageDeb, Name: "tzdata", Version: "2024a-0+deb12u1", Arch: "all"},
+	}
+	if !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("got %+v, want %+v", pkgs, want)
+	}
+
+	// the distroless files have no status
+	pkgs, err = parseDpkg(readFixture(t, "dpkg/status.d/base"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Package{
+		{Type: PackageDeb, Name: "base-files", Version: "12.4+deb12u5", Arch: "amd64", Source: "base-files"},
+	}
+	if !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("got %+v, want %+v", pkgs, want)
+	}
+}
+
+func TestParseApk(t *testing.T) {
+	pkgs, err := parseApk(readFixture(t, "apk/installed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Package{
+		{Type: PackageApk, Name: "musl", Version: "1.2.4_git20230717-r4", Arch: "x86_64", License: "MIT", Source: "musl"},
+		{Type: PackageApk, Name: "busybox", Version: "1.36.1-r15", Arch: "x86_64", License: "GPL-2.0-only", Source: "busybox"},
+	}
+	if !reflect.DeepEqual(pkgs, want) {
+		t.Errorf("got %+v, want %+v", pkgs, want)
+	}
+}
+
+func TestParseNpmLock(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []Package
+	}{
+		{
+			// links and the workspace packages they point at are not
+			// dependencies, a license object is not a license string
+			fixture: "npm/package-lock-v3.json",
+			want: []Package{
+				{Type: PackageNpm, Name: "@types/node", Version: "20.11.5"},
+				{Type: PackageNpm, Name: "debug", Version: "2.6.9", License: "MIT"},
+				{Type: PackageNpm, Name: "express", Version: "4.18.2", License: "MIT"},
+			},
+		},
+		{
+			fixture: "npm/package-lock-v1.json",
+			want: []Package{
+				{Type: PackageNpm, Name: "lodash", Version: "4.17.21"},
+				{Type: PackageNpm, Name: "minimist", Version: "1.2.8"},
+				{Type: PackageNpm, Name: "mkdirp", Version: "0.5.6"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			pkgs, err := parseNpmLock(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pkgs = sortPackages(pkgs); !reflect.DeepEqual(pkgs, tt.want) {
+				t.Errorf("got %+v, want %+v", pkgs, tt.want)
+			}
+		})
+	}
+
+	data := readFixture(t, "npm/package-lock-v3.json")
+	if _, err := parseNpmLock(data[:len(data)/2]); err == nil {
+		t.Error("no error for a truncated lockfile")
+	}
+}
+
+func TestParseGoBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := parseGoBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkgs[0].Name != "stdlib" || pkgs[0].Version != runtime.Version() {
+		t.Errorf("got %+v, want the stdlib of %s", pkgs[0], runtime.Version())
+	}
+	found := false
+	for _, pkg := range pkgs {
+		found = found || pkg.Name == "github.com/docker/docker"
+	}
+	if !found {
+		t.Errorf("the docker client is not among %+v", pkgs)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"elf magic only", append([]byte{}, elfMagic...)},
+		{"junk", append(append([]byte{}, elfMagic...), bytes.Repeat([]byte{0xff}, 4096)...)},
+		{"truncated", data[:len(data)/3]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseGoBinary(tt.data); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	want := Distro{ID: "debian", VersionID: "12", Name: "Debian GNU/Linux 12 (bookworm)"}
+	if got := parseOSRelease(readFixture(t, "os-release")); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := parseOSRelease([]byte("no equals sign\n=\n")); got != (Distro{}) {
+		t.Errorf("got %+v from junk", got)
+	}
+}
+
+func TestParserFor(t *testing.T) {
+	tests := []struct {
+		path  string
+		found bool
+	}{
+		{"/var/lib/dpkg/status", true},
+		{"/var/lib/dpkg/status-old", false},
+		{"/var/lib/dpkg/status.d/base", true},
+		{"/var/lib/dpkg/status.d/base.md5sums", false},
+		{"/lib/apk/db/installed", true},
+		{"/var/lib/rpm/rpmdb.sqlite", true},
+		{"/usr/lib/sysimage/rpm/Packages.db", true},
+		{"/home/rpm/rpmdb.sqlite", false},
+		{"/app/package-lock.json", true},
+		{"/app/node_modules/express/package-lock.json", false},
+		{"/usr/bin/app", false},
+	}
+
+	for _, tt := range tests {
+		if found := parserFor(tt.path) != nil; found != tt.found {
+			t.Errorf("%s: parser %v, want %v", tt.path, found, tt.found)
+		}
+	}
+}
+
+func TestPurl(t *testing.T) {
+	debian := Distro{ID: "debian", VersionID: "12"}
+	tests := []struct {
+		name   string
+		pkg    Package
+		distro Distro
+		want   string
+	}{
+		{
+			name:   "deb",
+			pkg:    Package{Type: PackageDeb, Name: "libssl3", Version: "3.0.11-1~deb12u2+b1", Arch: "amd64"},
+			distro: debian,
+			want:   "pkg:deb/debian/libssl3@3.0.11-1~deb12u2%2Bb1?arch=amd64&distro=debian-12",
+		},
+		{
+			name: "apk without a distro",
+			pkg:  Package{Type: PackageApk, Name: "musl", Version: "1.2.4-r4", Arch: "x86_64"},
+			want: "pkg:apk/alpine/musl@1.2.4-r4?arch=x86_64",
+		},
+		{
+			name:   "rpm epoch",
+			pkg:    Package{Type: PackageRPM, Name: "openssl-libs", Version: "1:3.2.1-2.fc40", Arch: "x86_64"},
+			distro: Distro{ID: "fedora", VersionID: "40"},
+			want:   "pkg:rpm/fedora/openssl-libs@3.2.1-2.fc40?arch=x86_64&distro=fedora-40&epoch=1",
+		},
+		{
+			name: "golang",
+			pkg:  Package{Type: PackageGolang, Name: "github.com/docker/docker", Version: "v28.5.2+incompatible"},
+			want: "pkg:golang/github.com/docker/docker@v28.5.2%2Bincompatible",
+		},
+		{
+			name: "golang devel",
+			pkg:  Package{Type: PackageGolang, Name: "example.com/app", Version: "(devel)"},
+			want: "pkg:golang/example.com/app",
+		},
+		{
+			name:   "npm scoped",
+			pkg:    Package{Type: PackageNpm, Name: "@types/node", Version: "20.11.5"},
+			distro: debian,
+			want:   "pkg:npm/%40types/node@20.11.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := purl(tt.pkg, tt.distro); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanArchive(t *testing.T) {
+	base := tarball(t, true,
+		tarFile{name: "etc/os-release", body: string(readFixture(t, "os-release"))},
+		tarFile{name: "var/lib/dpkg/status", body: string(readFixture(t, "dpkg/status"))},
+		tarFile{name: "app/package-lock.json", body: string(readFixture(t, "npm/package-lock-v1.json"))},
+		tarFile{name: "app/node_modules/x/package-lock.json", body: string(readFixture(t, "npm/package-lock-v3.json"))},
+	)
+	// the top layer drops the dpkg database and adds an executable that
+	// only looks like a binary
+	top := tarball(t, false,
+		tarFile{name: "var/lib/dpkg/.wh.status"},
+		tarFile{name: "lib/apk/db/installed", body: string(readFixture(t, "apk/installed"))},
+		tarFile{name: "usr/bin/junk", body: string(elfMagic) + "junk", mode: 0755},
+	)
+
+	sbom, err := scanArchive("demo", bytes.NewReader(savedArchive(t, base, top)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sbom.Reference != "demo:1" {
+		t.Errorf("reference %q, want demo:1", sbom.Reference)
+	}
+	if sbom.Distro.ID != "debian" {
+		t.Errorf("distro %+v, want debian", sbom.Distro)
+	}
+
+	var got []string
+	for _, pkg := range sbom.Packages {
+		got = append(got, pkg.Location+" "+pkg.PURL)
+	}
+	want := []string{
+		"/lib/apk/db/installed pkg:apk/debian/busybox@1.36.1-r15?arch=x86_64&distro=debian-12",
+		"/lib/apk/db/installed pkg:apk/debian/musl@1.2.4_git20230717-r4?arch=x86_64&distro=debian-12",
+		"/app/package-lock.json pkg:npm/lodash@4.17.21",
+		"/app/package-lock.json pkg:npm/minimist@1.2.8",
+		"/app/package-lock.json pkg:npm/mkdirp@0.5.6",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
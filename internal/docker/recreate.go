@@ -0,0 +1,252 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/presselam/yadc/internal/logger"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"time"
+)
+
+// ContainerDefinition is everything needed to create a container again.
+type ContainerDefinition struct {
+	Name             string                    `json:"Name"`
+	Config           *container.Config         `json:"Config"`
+	HostConfig       *container.HostConfig     `json:"HostConfig"`
+	NetworkingConfig *network.NetworkingConfig `json:"NetworkingConfig"`
+}
+
+// ContainerDefinitionOf reads the definition of an existing container,
+// leaving out what the engine filled in at runtime.
+func ContainerDefinitionOf(id string) (ContainerDefinition, error) {
+	logger.Trace(id)
+	var retval ContainerDefinition
+
+	docker, err := newClient()
+	if err != nil {
+		return retval, err
+	}
+	defer docker.Close()
+
+	inspect, err := docker.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return retval, err
+	}
+
+	shortID := inspect.ID
+	if len(shortID) > 12 {
+		shortID = shortID[0:12]
+	}
+
+	retval.Name = strings.TrimPrefix(inspect.Name, "/")
+	retval.Config = inspect.Config
+	retval.HostConfig = inspect.HostConfig
+	if retval.Config.Hostname == shortID {
+		retval.Config.Hostname = ""
+	}
+
+	retval.NetworkingConfig = &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{},
+	}
+	if inspect.NetworkSettings != nil {
+		for name, endpoint := range inspect.NetworkSettings.Networks {
+			var aliases []string
+			for _, alias := range endpoint.Aliases {
+				if alias != shortID {
+					aliases = append(aliases, alias)
+				}
+			}
+			retval.NetworkingConfig.EndpointsConfig[name] = &network.EndpointSettings{
+				IPAMConfig: endpoint.IPAMConfig,
+				Links:      endpoint.Links,
+				Aliases:    aliases,
+				DriverOpts: endpoint.DriverOpts,
+				GwPriority: endpoint.GwPriority,
+			}
+		}
+	}
+
+	return retval, nil
+}
+
+// EncodeDefinition renders the definition as YAML using the engine's
+// field names.
+func EncodeDefinition(def ContainerDefinition) ([]byte, error) {
+	return ToYAML(def)
+}
+
+// DecodeDefinition parses a definition written by EncodeDefinition.
+func DecodeDefinition(data []byte) (ContainerDefinition, error) {
+	var retval ContainerDefinition
+
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return retval, err
+	}
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return retval, err
+	}
+	if err := json.Unmarshal(buf, &retval); err != nil {
+		return retval, err
+	}
+	if retval.Config == nil || retval.Config.Image == "" {
+		return retval, errors.New("Config.Image is required")
+	}
+
+	return retval, nil
+}
+
+// ToYAML renders v as YAML going through its JSON encoding, so the keys
+// match what the engine API uses.
+func ToYAML(v any) ([]byte, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(doc)
+}
+
+// ContainerClone creates and starts a sibling container from def. A clone
+// that fails to start is removed again.
+func ContainerClone(def ContainerDefinition) (string, error) {
+	logger.Trace(def.Name)
+
+	docker, err := newClient()
+	if err != nil {
+		return "", err
+	}
+	defer docker.Close()
+
+	ctx := context.Background()
+	created, err := docker.ContainerCreate(ctx, def.Config, def.HostConfig, def.NetworkingConfig, nil, def.Name)
+	if err != nil {
+		return "", err
+	}
+
+	err = docker.ContainerStart(ctx, created.ID, container.StartOptions{})
+	if err != nil {
+		cleanup := docker.ContainerRemove(ctx, created.ID, container.RemoveOptions{Force: true})
+		return "", errors.Join(err, cleanup)
+	}
+
+	return created.ID, nil
+}
+
+// ContainerReplace swaps the container id for one created from def: the
+// original is stopped and renamed as a backup, the new one created and
+// started under the original name. Any failure rolls back to the original.
+func ContainerReplace(id string, def ContainerDefinition) (string, string, error) {
+	logger.Trace(id, def.Name)
+
+	docker, err := newClient()
+	if err != nil {
+		return "", "", err
+	}
+	defer docker.Close()
+
+	ctx := context.Background()
+	inspect, err := docker.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	name := strings.TrimPrefix(inspect.Name, "/")
+	if def.Name == "" {
+		def.Name = name
+	}
+	backup := fmt.Sprintf("%s-backup-%s", name, time.Now().Format("20060102150405"))
+	running := inspect.State.Running
+
+	if running {
+		if err := docker.ContainerStop(ctx, inspect.ID, container.StopOptions{}); err != nil {
+			return "", "", err
+		}
+	}
+
+	rollback := func(cause error, createdID string) error {
+		errs := []error{cause}
+		if createdID != "" {
+			errs = append(errs, docker.ContainerRemove(ctx, createdID, container.RemoveOptions{Force: true}))
+		}
+		errs = append(errs, docker.ContainerRename(ctx, inspect.ID, name))
+		if running {
+			errs = append(errs, docker.ContainerStart(ctx, inspect.ID, container.StartOptions{}))
+		}
+		logger.Warn("docker.replace.rollback:", id)
+		return errors.Join(errs...)
+	}
+
+	if err := docker.ContainerRename(ctx, inspect.ID, backup); err != nil {
+		if running {
+			err = errors.Join(err, docker.ContainerStart(ctx, inspect.ID, container.StartOptions{}))
+		}
+		return "", "", err
+	}
+
+	def.HostConfig = keepVolumes(def.HostConfig, inspect.Mounts)
+	created, err := docker.ContainerCreate(ctx, def.Config, def.HostConfig, def.NetworkingConfig, nil, def.Name)
+	if err != nil {
+		return "", "", rollback(err, "")
+	}
+
+	if err := docker.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		return "", "", rollback(err, created.ID)
+	}
+
+	logger.Info("Replaced: ", name, " backup: ", backup)
+	return created.ID, backup, nil
+}
+
+// keepVolumes mounts the volumes of the original container that the host
+// config does not mention, the anonymous ones of the image or a bare -v,
+// so the replacement sees the same data rather than fresh empty volumes.
+func keepVolumes(host *container.HostConfig, mounts []container.MountPoint) *container.HostConfig {
+	retval := container.HostConfig{}
+	if host != nil {
+		retval = *host
+	}
+
+	targets := make(map[string]bool)
+	for _, bind := range retval.Binds {
+		parts := strings.Split(bind, ":")
+		if len(parts) > 1 {
+			targets[parts[1]] = true
+		} else {
+			targets[parts[0]] = true
+		}
+	}
+	for _, m := range retval.Mounts {
+		targets[m.Target] = true
+	}
+	for target := range retval.Tmpfs {
+		targets[target] = true
+	}
+
+	var kept []mount.Mount
+	for _, m := range mounts {
+		if m.Type != mount.TypeVolume || m.Name == "" || targets[m.Destination] {
+			continue
+		}
+		kept = append(kept, mount.Mount{
+			Type:     mount.TypeVolume,
+			Source:   m.Name,
+			Target:   m.Destination,
+			ReadOnly: !m.RW,
+		})
+	}
+	if len(kept) > 0 {
+		retval.Mounts = append(append([]mount.Mount(nil), retval.Mounts...), kept...)
+	}
+	return &retval
+}
@@ -0,0 +1,42 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+)
+
+func TestKeepVolumes(t *testing.T) {
+	host := &container.HostConfig{
+		Binds:  []string{"named:/named", "/srv/www:/www:ro"},
+		Mounts: []mount.Mount{{Type: mount.TypeVolume, Source: "cache", Target: "/cache"}},
+		Tmpfs:  map[string]string{"/tmp": ""},
+	}
+	mounts := []container.MountPoint{
+		{Type: mount.TypeVolume, Name: "named", Destination: "/named", RW: true},
+		{Type: mount.TypeBind, Source: "/srv/www", Destination: "/www"},
+		{Type: mount.TypeVolume, Name: "cache", Destination: "/cache", RW: true},
+		{Type: mount.TypeVolume, Name: "0123abcd", Destination: "/var/lib/postgresql/data", RW: true},
+		{Type: mount.TypeVolume, Name: "4567ef", Destination: "/config"},
+	}
+
+	// only the anonymous volumes are added, behind what was there
+	want := []mount.Mount{
+		{Type: mount.TypeVolume, Source: "cache", Target: "/cache"},
+		{Type: mount.TypeVolume, Source: "0123abcd", Target: "/var/lib/postgresql/data"},
+		{Type: mount.TypeVolume, Source: "4567ef", Target: "/config", ReadOnly: true},
+	}
+	got := keepVolumes(host, mounts)
+	if !reflect.DeepEqual(got.Mounts, want) {
+		t.Errorf("got %+v, want %+v", got.Mounts, want)
+	}
+	if len(host.Mounts) != 1 {
+		t.Errorf("the definition was changed: %+v", host.Mounts)
+	}
+
+	if got := keepVolumes(nil, nil); got == nil || got.Mounts != nil {
+		t.Errorf("got %+v from nothing", got)
+	}
+}
@@ -0,0 +1,422 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+)
+
+// rpm keeps its database in sqlite on current distributions and in its own
+// ndb format on SUSE. Both only wrap the same header blobs, so neither
+// needs more than a reader for the container format.
+
+const (
+	rpmTagName      = 1000
+	rpmTagVersion   = 1001
+	rpmTagRelease   = 1002
+	rpmTagEpoch     = 1003
+	rpmTagLicense   = 1014
+	rpmTagArch      = 1022
+	rpmTagSourceRPM = 1044
+
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+
+	ndbPageSize   = 4096
+	ndbHeaderSize = 32
+	ndbSlotSize   = 16
+	ndbBlockSize  = 16
+	ndbMagic      = "RpmP"
+	ndbSlotMagic  = "Slot"
+	ndbBlobMagic  = "BlbS"
+
+	sqliteMagic      = "SQLite format 3\x00"
+	sqliteHeaderSize = 100
+	sqliteMaxDepth   = 32
+)
+
+var errCorrupt = errors.New("corrupt package database")
+
+// parseRpmSqlite reads the Packages table of rpmdb.sqlite.
+func parseRpmSqlite(data []byte) ([]Package, error) {
+	db, err := openSQLite(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var root int64
+	err = db.walk(1, func(record []sqliteValue) error {
+		if len(record) > 3 && record[0].text() == "table" && record[1].text() == "Packages" {
+			root = record[3].integer()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if root == 0 {
+		return nil, errors.New("rpmdb has no Packages table")
+	}
+
+	var retval []Package
+	err = db.walk(uint32(root), func(record []sqliteValue) error {
+		if len(record) < 2 {
+			return nil
+		}
+		pkg, ok := parseRpmHeader(record[1].raw)
+		if ok {
+			retval = append(retval, pkg)
+		}
+		return nil
+	})
+
+	return retval, err
+}
+
+// parseRpmNdb reads the slot pages of an ndb Packages.db and the header
+// blob each used slot points at.
+func parseRpmNdb(data []byte) ([]Package, error) {
+	if len(data) < ndbHeaderSize || string(data[0:4]) != ndbMagic {
+		return nil, errors.New("not an ndb database")
+	}
+
+	end := int(binary.LittleEndian.Uint32(data[12:16])) * ndbPageSize
+	if end > len(data) {
+		return nil, errCorrupt
+	}
+
+	var retval []Package
+	for off := ndbHeaderSize; off+ndbSlotSize <= end; off += ndbSlotSize {
+		slot := data[off : off+ndbSlotSize]
+		index := binary.LittleEndian.Uint32(slot[4:8])
+		if string(slot[0:4]) != ndbSlotMagic || index == 0 {
+			continue
+		}
+
+		blob := int(binary.LittleEndian.Uint32(slot[8:12])) * ndbBlockSize
+		if blob+16 > len(data) || string(data[blob:blob+4]) != ndbBlobMagic {
+			return retval, errCorrupt
+		}
+		if binary.LittleEndian.Uint32(data[blob+4:blob+8]) != index {
+			return retval, errCorrupt
+		}
+		length := int(binary.LittleEndian.Uint32(data[blob+12 : blob+16]))
+		if blob+16+length > len(data) {
+			return retval, errCorrupt
+		}
+
+		pkg, ok := parseRpmHeader(data[blob+16 : blob+16+length])
+		if ok {
+			retval = append(retval, pkg)
+		}
+	}
+
+	return retval, nil
+}
+
+// parseRpmHeader picks the package tags out of a header blob: a count of
+// index entries and the size of the data store, the entries, then the
+// store itself.
+func parseRpmHeader(blob []byte) (Package, bool) {
+	retval := Package{Type: PackageRPM}
+	if len(blob) < 8 {
+		return retval, false
+	}
+
+	count := int(binary.BigEndian.Uint32(blob[0:4]))
+	size := int(binary.BigEndian.Uint32(blob[4:8]))
+	store := 8 + count*16
+	if count < 0 || size < 0 || store+size > len(blob) {
+		return retval, false
+	}
+	data := blob[store : store+size]
+
+	var version, release, epoch string
+	for i := 0; i < count; i++ {
+		entry := blob[8+i*16 : 8+(i+1)*16]
+		tag := binary.BigEndian.Uint32(entry[0:4])
+		kind := binary.BigEndian.Uint32(entry[4:8])
+		offset := int(int32(binary.BigEndian.Uint32(entry[8:12])))
+		if offset < 0 || offset >= len(data) {
+			continue
+		}
+
+		var value string
+		switch kind {
+		case rpmTypeString, rpmTypeStringArray, rpmTypeI18NString:
+			value = string(data[offset:])
+			if end := bytes.IndexByte(data[offset:], 0); end >= 0 {
+				value = string(data[offset : offset+end])
+			}
+		case rpmTypeInt32:
+			if offset+4 > len(data) {
+				continue
+			}
+			value = strconv.FormatUint(uint64(binary.BigEndian.Uint32(data[offset:offset+4])), 10)
+		default:
+			continue
+		}
+
+		switch tag {
+		case rpmTagName:
+			retval.Name = value
+		case rpmTagVersion:
+			version = value
+		case rpmTagRelease:
+			release = value
+		case rpmTagEpoch:
+			epoch = value
+		case rpmTagLicense:
+			retval.License = value
+		case rpmTagArch:
+			retval.Arch = value
+		case rpmTagSourceRPM:
+			retval.Source = value
+		}
+	}
+
+	// the signing keys are stored as fake packages
+	if retval.Name == "" || retval.Name == "gpg-pubkey" {
+		return retval, false
+	}
+
+	retval.Version = version
+	if release != "" {
+		retval.Version += "-" + release
+	}
+	if epoch != "" && epoch != "0" {
+		retval.Version = epoch + ":" + retval.Version
+	}
+
+	return retval, true
+}
+
+// sqliteDB reads the table b-trees of an sqlite file held in memory, which
+// is all it takes to get the rows out of a table.
+type sqliteDB struct {
+	data     []byte
+	pageSize int
+	usable   int
+}
+
+type sqliteValue struct {
+	kind int64
+	raw  []byte
+}
+
+func openSQLite(data []byte) (*sqliteDB, error) {
+	if len(data) < sqliteHeaderSize || string(data[0:16]) != sqliteMagic {
+		return nil, errors.New("not an sqlite database")
+	}
+
+	size := int(binary.BigEndian.Uint16(data[16:18]))
+	if size == 1 {
+		size = 65536
+	}
+	if size < 512 {
+		return nil, errCorrupt
+	}
+
+	return &sqliteDB{data: data, pageSize: size, usable: size - int(data[20])}, nil
+}
+
+func (db *sqliteDB) page(n uint32) ([]byte, error) {
+	off := (int(n) - 1) * db.pageSize
+	if n == 0 || off+db.pageSize > len(db.data) {
+		return nil, errCorrupt
+	}
+	return db.data[off : off+db.pageSize], nil
+}
+
+// walk visits every row of the table b-tree rooted at page n.
+func (db *sqliteDB) walk(n uint32, visit func([]sqliteValue) error) error {
+	return db.walkPage(n, 0, make(map[uint32]bool), visit)
+}
+
+// walkPage visits the rows under page n. A page turning up twice means the
+// tree has loops and is not one.
+func (db *sqliteDB) walkPage(n uint32, depth int, seen map[uint32]bool, visit func([]sqliteValue) error) error {
+	if depth > sqliteMaxDepth || seen[n] {
+		return errCorrupt
+	}
+	seen[n] = true
+
+	page, err := db.page(n)
+	if err != nil {
+		return err
+	}
+	hdr := 0
+	if n == 1 {
+		hdr = sqliteHeaderSize
+	}
+
+	cells := int(binary.BigEndian.Uint16(page[hdr+3 : hdr+5]))
+	switch page[hdr] {
+	case 0x0d: // leaf
+		pointers := hdr + 8
+		if pointers+cells*2 > len(page) {
+			return errCorrupt
+		}
+		for i := 0; i < cells; i++ {
+			ptr := int(binary.BigEndian.Uint16(page[pointers+i*2:]))
+			if ptr >= len(page) {
+				return errCorrupt
+			}
+			cell := page[ptr:]
+			size, a := sqliteVarint(cell)
+			_, b := sqliteVarint(cell[a:])
+			payload, err := db.payload(cell[a+b:], size)
+			if err != nil {
+				return err
+			}
+			record, err := sqliteRecord(payload)
+			if err != nil {
+				return err
+			}
+			if err := visit(record); err != nil {
+				return err
+			}
+		}
+	case 0x05: // interior
+		pointers := hdr + 12
+		if pointers+cells*2 > len(page) {
+			return errCorrupt
+		}
+		for i := 0; i < cells; i++ {
+			ptr := int(binary.BigEndian.Uint16(page[pointers+i*2:]))
+			if ptr+4 > len(page) {
+				return errCorrupt
+			}
+			if err := db.walkPage(binary.BigEndian.Uint32(page[ptr:]), depth+1, seen, visit); err != nil {
+				return err
+			}
+		}
+		return db.walkPage(binary.BigEndian.Uint32(page[hdr+8:hdr+12]), depth+1, seen, visit)
+	default:
+		return errCorrupt
+	}
+
+	return nil
+}
+
+// payload gathers a cell's payload, following the overflow chain when it
+// does not fit on the page.
+func (db *sqliteDB) payload(cell []byte, size int64) ([]byte, error) {
+	// no payload is larger than the file holding it
+	if size < 0 || size > int64(len(db.data)) {
+		return nil, errCorrupt
+	}
+
+	max := int64(db.usable - 35)
+	if size <= max {
+		if size > int64(len(cell)) {
+			return nil, errCorrupt
+		}
+		return cell[:size], nil
+	}
+
+	min := int64((db.usable-12)*32/255 - 23)
+	local := min + (size-min)%int64(db.usable-4)
+	if local > max {
+		local = min
+	}
+	if local+4 > int64(len(cell)) {
+		return nil, errCorrupt
+	}
+
+	retval := make([]byte, 0, size)
+	retval = append(retval, cell[:local]...)
+	next := binary.BigEndian.Uint32(cell[local:])
+	for int64(len(retval)) < size {
+		page, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		next = binary.BigEndian.Uint32(page[0:4])
+		chunk := page[4:db.usable]
+		if need := size - int64(len(retval)); int64(len(chunk)) > need {
+			chunk = chunk[:need]
+		}
+		retval = append(retval, chunk...)
+	}
+
+	return retval, nil
+}
+
+func sqliteRecord(payload []byte) ([]sqliteValue, error) {
+	size, n := sqliteVarint(payload)
+	if size < 0 || size > int64(len(payload)) {
+		return nil, errCorrupt
+	}
+
+	var retval []sqliteValue
+	body := int(size)
+	for n < int(size) {
+		kind, m := sqliteVarint(payload[n:size])
+		n += m
+
+		length := 0
+		switch {
+		case kind >= 12:
+			length = int((kind - 12) / 2)
+		case kind >= 1 && kind <= 4:
+			length = int(kind)
+		case kind == 5:
+			length = 6
+		case kind == 6 || kind == 7:
+			length = 8
+		}
+		if body+length > len(payload) {
+			return nil, errCorrupt
+		}
+
+		retval = append(retval, sqliteValue{kind: kind, raw: payload[body : body+length]})
+		body += length
+	}
+
+	return retval, nil
+}
+
+func sqliteVarint(buf []byte) (int64, int) {
+	var retval int64
+	for i := 0; i < 9 && i < len(buf); i++ {
+		if i == 8 {
+			return retval<<8 | int64(buf[i]), 9
+		}
+		retval = retval<<7 | int64(buf[i]&0x7f)
+		if buf[i] < 0x80 {
+			return retval, i + 1
+		}
+	}
+	return retval, len(buf)
+}
+
+func (v sqliteValue) text() string {
+	if v.kind < 13 || v.kind%2 == 0 {
+		return ""
+	}
+	return string(v.raw)
+}
+
+func (v sqliteValue) integer() int64 {
+	switch v.kind {
+	case 8:
+		return 0
+	case 9:
+		return 1
+	}
+	if v.kind < 1 || v.kind > 6 {
+		return 0
+	}
+
+	var retval int64
+	for _, b := range v.raw {
+		retval = retval<<8 | int64(b)
+	}
+	// sign extend
+	shift := 64 - 8*len(v.raw)
+	return retval << shift >> shift
+}
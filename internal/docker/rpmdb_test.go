@@ -0,0 +1,294 @@
+package docker
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The rpm fixtures hold the same made up headers: rpmdb.sqlite was written
+// by sqlite itself with 512 byte pages, so the Packages table spans
+// interior pages and the long license spills onto overflow pages.
+// Packages.db holds the first four in ndb slots.
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// rpmHeader builds a header blob: the entry count and store size, the
+// entries, then the store.
+func rpmHeader(tags map[uint32]string) []byte {
+	var entries, store []byte
+	for tag, value := range tags {
+		entry := make([]byte, 16)
+		binary.BigEndian.PutUint32(entry[0:], tag)
+		binary.BigEndian.PutUint32(entry[4:], rpmTypeString)
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(store)))
+		binary.BigEndian.PutUint32(entry[12:], 1)
+		entries = append(entries, entry...)
+		store = append(store, value+"\x00"...)
+	}
+
+	retval := make([]byte, 8)
+	binary.BigEndian.PutUint32(retval[0:], uint32(len(tags)))
+	binary.BigEndian.PutUint32(retval[4:], uint32(len(store)))
+	return append(append(retval, entries...), store...)
+}
+
+func packagesByName(pkgs []Package) map[string]Package {
+	retval := make(map[string]Package)
+	for _, pkg := range pkgs {
+		retval[pkg.Name] = pkg
+	}
+	return retval
+}
+
+func TestParseRpmSqlite(t *testing.T) {
+	pkgs, err := parseRpmSqlite(readFixture(t, "rpm/rpmdb.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 45 headers, less the signing key
+	if len(pkgs) != 44 {
+		t.Errorf("got %d packages, want 44", len(pkgs))
+	}
+
+	byName := packagesByName(pkgs)
+	tests := []Package{
+		{Type: PackageRPM, Name: "bash", Version: "5.2.26-3.fc40", Arch: "x86_64", License: "GPL-3.0-or-later", Source: "bash-5.2.26-3.fc40.src.rpm"},
+		{Type: PackageRPM, Name: "openssl-libs", Version: "1:3.2.1-2.fc40", Arch: "x86_64", License: "Apache-2.0", Source: "openssl-libs-3.2.1-2.fc40.src.rpm"},
+		{Type: PackageRPM, Name: "zero-epoch", Version: "1.0-1", Arch: "noarch", License: "MIT", Source: "zero-epoch-1.0-1.src.rpm"},
+		{Type: PackageRPM, Name: "filler-39", Version: "1.39-1", Arch: "noarch", License: "MIT", Source: "filler-39-1.39-1.src.rpm"},
+	}
+	for _, want := range tests {
+		if got := byName[want.Name]; got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+
+	if _, ok := byName["gpg-pubkey"]; ok {
+		t.Error("signing key listed as a package")
+	}
+	license := byName["licenses"].License
+	if !strings.HasPrefix(license, "MIT AND License-0 AND") || !strings.HasSuffix(license, "License-119") {
+		t.Errorf("overflowing license read as %q", license)
+	}
+}
+
+func TestParseRpmNdb(t *testing.T) {
+	pkgs, err := parseRpmNdb(readFixture(t, "rpm/Packages.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name)
+	}
+	if got := strings.Join(names, ","); got != "bash,openssl-libs,zero-epoch" {
+		t.Errorf("got %s, want bash,openssl-libs,zero-epoch", got)
+	}
+}
+
+func TestParseRpmHeader(t *testing.T) {
+	valid := rpmHeader(map[uint32]string{
+		rpmTagName:    "curl",
+		rpmTagVersion: "8.6.0",
+		rpmTagRelease: "7.fc40",
+		rpmTagArch:    "aarch64",
+	})
+
+	tests := []struct {
+		name string
+		blob []byte
+		want string
+	}{
+		{"valid", valid, "8.6.0-7.fc40"},
+		{"empty", nil, ""},
+		{"short", valid[:6], ""},
+		{"truncated store", valid[:len(valid)-4], ""},
+		{"no name", rpmHeader(map[uint32]string{rpmTagVersion: "1"}), ""},
+		{"huge count", append([]byte{0xff, 0xff, 0xff, 0xff}, valid[4:]...), ""},
+		{"huge size", append(append([]byte{}, valid[:4]...), append([]byte{0xff, 0xff, 0xff, 0xff}, valid[8:]...)...), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, ok := parseRpmHeader(tt.blob)
+			if ok != (tt.want != "") || (ok && pkg.Version != tt.want) {
+				t.Errorf("got %+v %v, want version %q", pkg, ok, tt.want)
+			}
+		})
+	}
+
+	// entries pointing past the store are skipped rather than read
+	blob := append([]byte{}, valid...)
+	binary.BigEndian.PutUint32(blob[8+8:], 1<<30)
+	binary.BigEndian.PutUint32(blob[8+16+8:], 0xffffffff)
+	parseRpmHeader(blob)
+}
+
+// sqliteTreePage finds the root page of the Packages table.
+func sqliteTreePage(t *testing.T, data []byte) uint32 {
+	t.Helper()
+	db, err := openSQLite(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var root uint32
+	err = db.walk(1, func(record []sqliteValue) error {
+		if len(record) > 3 && record[1].text() == "Packages" {
+			root = uint32(record[3].integer())
+		}
+		return nil
+	})
+	if err != nil || root == 0 {
+		t.Fatal("no Packages table in the fixture", err)
+	}
+	return root
+}
+
+func TestParseRpmSqliteCorrupt(t *testing.T) {
+	data := readFixture(t, "rpm/rpmdb.sqlite")
+	const pageSize = 512
+	root := sqliteTreePage(t, data)
+	rootOff := int(root-1) * pageSize
+	if data[rootOff] != 0x05 {
+		t.Fatalf("root page of the fixture is not an interior page: %x", data[rootOff])
+	}
+
+	// firstLeaf is the page the first cell of the root points at
+	ptr := int(binary.BigEndian.Uint16(data[rootOff+12:]))
+	firstLeaf := int(binary.BigEndian.Uint32(data[rootOff+ptr:])) - 1
+	leafCell := firstLeaf*pageSize + int(binary.BigEndian.Uint16(data[firstLeaf*pageSize+8:]))
+
+	tests := []struct {
+		name   string
+		mutate func(data []byte) []byte
+	}{
+		{"not sqlite", func([]byte) []byte { return []byte("hello") }},
+		{"header only", func(d []byte) []byte { return d[:sqliteHeaderSize] }},
+		{"truncated", func(d []byte) []byte { return d[:len(d)/2] }},
+		{"small pages", func(d []byte) []byte {
+			binary.BigEndian.PutUint16(d[16:], 256)
+			return d
+		}},
+		{"bad page type", func(d []byte) []byte {
+			d[rootOff] = 0x42
+			return d
+		}},
+		{"cell pointer past page", func(d []byte) []byte {
+			binary.BigEndian.PutUint16(d[rootOff+12:], 0xffff)
+			return d
+		}},
+		{"child out of range", func(d []byte) []byte {
+			binary.BigEndian.PutUint32(d[rootOff+8:], 0xfffffff0)
+			return d
+		}},
+		{"loop", func(d []byte) []byte {
+			binary.BigEndian.PutUint32(d[rootOff+8:], root)
+			return d
+		}},
+		{"shared child", func(d []byte) []byte {
+			binary.BigEndian.PutUint32(d[rootOff+8:], uint32(firstLeaf+1))
+			return d
+		}},
+		{"huge payload", func(d []byte) []byte {
+			d[leafCell] = 0xff
+			d[leafCell+1] = 0xff
+			d[leafCell+2] = 0x7f
+			return d
+		}},
+		{"negative payload", func(d []byte) []byte {
+			for i := 0; i < 9; i++ {
+				d[leafCell+i] = 0xff
+			}
+			return d
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt := tt.mutate(append([]byte{}, data...))
+			if _, err := parseRpmSqlite(corrupt); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestParseRpmNdbCorrupt(t *testing.T) {
+	data := readFixture(t, "rpm/Packages.db")
+	// the first slot, and the blob it points at
+	slot := ndbHeaderSize
+	blob := int(binary.LittleEndian.Uint32(data[slot+8:])) * ndbBlockSize
+
+	tests := []struct {
+		name   string
+		mutate func(data []byte) []byte
+	}{
+		{"not ndb", func([]byte) []byte { return []byte("RpmQ and then some more bytes that are not ndb") }},
+		{"short", func(d []byte) []byte { return d[:16] }},
+		{"slot pages past end", func(d []byte) []byte {
+			binary.LittleEndian.PutUint32(d[12:], 1000)
+			return d
+		}},
+		{"blob past end", func(d []byte) []byte {
+			binary.LittleEndian.PutUint32(d[slot+8:], 0xffffffff)
+			return d
+		}},
+		{"blob magic", func(d []byte) []byte {
+			copy(d[blob:], "XXXX")
+			return d
+		}},
+		{"blob of another slot", func(d []byte) []byte {
+			binary.LittleEndian.PutUint32(d[blob+4:], 99)
+			return d
+		}},
+		{"blob length", func(d []byte) []byte {
+			binary.LittleEndian.PutUint32(d[blob+12:], 0xffffffff)
+			return d
+		}},
+		{"truncated", func(d []byte) []byte { return d[:len(d)-32] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt := tt.mutate(append([]byte{}, data...))
+			if _, err := parseRpmNdb(corrupt); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+// TestRpmDamage cuts and scribbles over the fixtures everywhere, the
+// readers have to come back with or without an error but never panic.
+func TestRpmDamage(t *testing.T) {
+	parsers := map[string]packageParser{
+		"rpm/rpmdb.sqlite": parseRpmSqlite,
+		"rpm/Packages.db":  parseRpmNdb,
+	}
+
+	for name, parse := range parsers {
+		data := readFixture(t, name)
+		for n := 0; n < len(data); n += 7 {
+			parse(data[:n])
+
+			for _, b := range []byte{0x00, 0x7f, 0xff} {
+				damaged := append([]byte{}, data...)
+				damaged[n] = b
+				parse(damaged)
+			}
+		}
+	}
+}
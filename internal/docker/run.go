@@ -0,0 +1,256 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	"github.com/presselam/yadc/internal/logger"
+	"strconv"
+	"strings"
+)
+
+// RunSpec holds the settings of a container started from the run wizard.
+// List values use shell style quoting, e.g. `A=1 "B=two words"`.
+type RunSpec struct {
+	Image   string
+	Name    string
+	Command string
+	Env     string
+	Ports   string
+	Volumes string
+	Network string
+	Restart string
+	CPUs    string
+	Memory  string
+}
+
+// ContainerRun creates and starts a container from spec, returning the new
+// container ID. A container that fails to start is removed again.
+func ContainerRun(spec RunSpec) (string, error) {
+	logger.Trace(spec.Image, spec.Name)
+
+	config, hostConfig, networking, err := spec.Config()
+	if err != nil {
+		return "", err
+	}
+
+	docker, err := newClient()
+	if err != nil {
+		return "", err
+	}
+	defer docker.Close()
+
+	created, err := docker.ContainerCreate(context.Background(), config, hostConfig, networking, nil, spec.Name)
+	if err != nil {
+		return "", err
+	}
+	for _, warning := range created.Warnings {
+		logger.Warn("docker.run:", warning)
+	}
+
+	err = docker.ContainerStart(context.Background(), created.ID, container.StartOptions{})
+	if err != nil {
+		cleanup := docker.ContainerRemove(context.Background(), created.ID, container.RemoveOptions{Force: true})
+		return "", errors.Join(err, cleanup)
+	}
+
+	return created.ID, nil
+}
+
+// Config translates the spec into the engine's create configuration.
+func (spec RunSpec) Config() (*container.Config, *container.HostConfig, *network.NetworkingConfig, error) {
+	if spec.Image == "" {
+		return nil, nil, nil, errors.New("image is required")
+	}
+
+	command, err := SplitArgs(spec.Command)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	env, err := SplitArgs(spec.Env)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ports, err := SplitArgs(spec.Ports)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	volumes, err := SplitArgs(spec.Volumes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	exposed, bindings, err := nat.ParsePortSpecs(ports)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	restart, err := ParseRestartPolicy(spec.Restart)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	config := &container.Config{
+		Image:        spec.Image,
+		Env:          env,
+		ExposedPorts: exposed,
+	}
+	if len(command) > 0 {
+		config.Cmd = command
+	}
+
+	hostConfig := &container.HostConfig{
+		Binds:         volumes,
+		PortBindings:  bindings,
+		RestartPolicy: restart,
+		NetworkMode:   container.NetworkMode(spec.Network),
+	}
+
+	if spec.CPUs != "" {
+		cpus, err := strconv.ParseFloat(spec.CPUs, 64)
+		if err != nil {
+			return nil, nil, nil, errors.New("invalid cpus: [" + spec.CPUs + "]")
+		}
+		hostConfig.NanoCPUs = int64(cpus * 1e9)
+	}
+	if spec.Memory != "" {
+		memory, err := units.RAMInBytes(spec.Memory)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		hostConfig.Memory = memory
+	}
+
+	var networking *network.NetworkingConfig
+	if spec.Network != "" && hostConfig.NetworkMode.IsUserDefined() {
+		networking = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{spec.Network: {}},
+		}
+	}
+
+	return config, hostConfig, networking, nil
+}
+
+// CommandLine renders the equivalent docker run invocation.
+func (spec RunSpec) CommandLine() string {
+	args := []string{"docker", "run", "-d"}
+
+	add := func(flag string, values string) {
+		list, err := SplitArgs(values)
+		if err != nil {
+			list = []string{values}
+		}
+		for _, v := range list {
+			args = append(args, flag, quoteArg(v))
+		}
+	}
+
+	if spec.Name != "" {
+		args = append(args, "--name", quoteArg(spec.Name))
+	}
+	add("-e", spec.Env)
+	add("-p", spec.Ports)
+	add("-v", spec.Volumes)
+	if spec.Network != "" {
+		args = append(args, "--network", quoteArg(spec.Network))
+	}
+	if spec.Restart != "" {
+		args = append(args, "--restart", quoteArg(spec.Restart))
+	}
+	if spec.CPUs != "" {
+		args = append(args, "--cpus", quoteArg(spec.CPUs))
+	}
+	if spec.Memory != "" {
+		args = append(args, "--memory", quoteArg(spec.Memory))
+	}
+	args = append(args, quoteArg(spec.Image))
+
+	command, err := SplitArgs(spec.Command)
+	if err != nil {
+		command = []string{spec.Command}
+	}
+	for _, c := range command {
+		args = append(args, quoteArg(c))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// ParseRestartPolicy reads a policy as given to --restart, e.g.
+// "on-failure:3".
+func ParseRestartPolicy(value string) (container.RestartPolicy, error) {
+	var retval container.RestartPolicy
+	if value == "" {
+		return retval, nil
+	}
+
+	name, count, found := strings.Cut(value, ":")
+	retval.Name = container.RestartPolicyMode(name)
+	if found {
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return retval, errors.New("invalid restart count: [" + value + "]")
+		}
+		retval.MaximumRetryCount = n
+	}
+
+	return retval, container.ValidateRestartPolicy(retval)
+}
+
+// SplitArgs splits s on whitespace honouring single and double quotes and
+// backslash escapes.
+func SplitArgs(s string) ([]string, error) {
+	var retval []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				retval = append(retval, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return retval, errors.New("unterminated quote: [" + s + "]")
+	}
+	if inArg {
+		retval = append(retval, current.String())
+	}
+
+	return retval, nil
+}
+
+func quoteArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?;&|<>()") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,416 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/presselam/yadc/internal/logger"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SBOMFormatSPDX      = "spdx"
+	SBOMFormatCycloneDX = "cyclonedx"
+
+	maxBinary = 256 << 20
+)
+
+var elfMagic = []byte("\x7fELF")
+
+// SBOM is the package inventory of an image, read offline from its layers.
+type SBOM struct {
+	ID        string
+	Reference string
+	Distro    Distro
+	Packages  []Package
+}
+
+// layerScan is what a single layer contributes: the packages of every
+// database or binary it holds and what it deletes from the layers below.
+type layerScan struct {
+	found    map[string][]Package
+	releases map[string]Distro
+	removed  []string
+	opaque   []string
+}
+
+// ImageSBOM reads the image archive produced by the save API and lists the
+// packages of the final filesystem: dpkg, apk and rpm databases, Go
+// binaries and npm lockfiles. Nothing leaves the machine.
+func ImageSBOM(id string) (SBOM, error) {
+	logger.Trace(id)
+
+	docker, err := newClient()
+	if err != nil {
+		return SBOM{ID: id}, err
+	}
+	defer docker.Close()
+
+	data, err := docker.ImageSave(context.Background(), []string{id})
+	if err != nil {
+		return SBOM{ID: id}, err
+	}
+	defer data.Close()
+
+	return scanArchive(id, data)
+}
+
+func scanArchive(id string, archive io.Reader) (SBOM, error) {
+	retval := SBOM{ID: id}
+
+	scans := make(map[string]layerScan)
+	saved, err := walkArchive(archive, func(name string, r io.Reader) error {
+		scan, err := scanLayer(r)
+		scans[name] = scan
+		return err
+	})
+	if err != nil {
+		return retval, err
+	}
+	if len(saved.manifest.RepoTags) > 0 {
+		retval.Reference = saved.manifest.RepoTags[0]
+	}
+
+	// replay the layers so only what survives to the final image counts
+	found := make(map[string][]Package)
+	releases := make(map[string]Distro)
+	for _, name := range saved.layers {
+		scan := scans[name]
+		for _, dir := range scan.opaque {
+			removePrefix(found, releases, dir+"/")
+		}
+		for _, p := range scan.removed {
+			delete(found, p)
+			delete(releases, p)
+			removePrefix(found, releases, p+"/")
+		}
+		for p, pkgs := range scan.found {
+			found[p] = pkgs
+		}
+		for p, distro := range scan.releases {
+			releases[p] = distro
+		}
+	}
+
+	for _, p := range []string{"/usr/lib/os-release", "/etc/os-release"} {
+		if distro, ok := releases[p]; ok {
+			retval.Distro = distro
+		}
+	}
+
+	for p, pkgs := range found {
+		for _, pkg := range pkgs {
+			pkg.Location = p
+			pkg.PURL = purl(pkg, retval.Distro)
+			retval.Packages = append(retval.Packages, pkg)
+		}
+	}
+	sort.Slice(retval.Packages, func(i, j int) bool {
+		a, b := retval.Packages[i], retval.Packages[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Location < b.Location
+	})
+
+	return retval, nil
+}
+
+func removePrefix(found map[string][]Package, releases map[string]Distro, prefix string) {
+	for p := range found {
+		if strings.HasPrefix(p, prefix) {
+			delete(found, p)
+		}
+	}
+	for p := range releases {
+		if strings.HasPrefix(p, prefix) {
+			delete(releases, p)
+		}
+	}
+}
+
+// scanLayer reads the files of a layer we know how to parse. A database
+// that does not parse is logged and skipped, the rest of the image is
+// still worth listing.
+func scanLayer(r io.Reader) (layerScan, error) {
+	retval := layerScan{
+		found:    make(map[string][]Package),
+		releases: make(map[string]Distro),
+	}
+
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(2); bytes.Equal(head, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return retval, err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	tr := tar.NewReader(br)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return retval, err
+		}
+
+		name := path.Clean("/" + hdr.Name)
+		dir, base := path.Split(name)
+		switch {
+		case base == whiteoutOpaque:
+			retval.opaque = append(retval.opaque, path.Clean(dir))
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			retval.removed = append(retval.removed, path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+			continue
+		case hdr.Typeflag != tar.TypeReg:
+			continue
+		}
+
+		if isOSRelease(name) && hdr.Size < maxMetadata {
+			buf, err := io.ReadAll(tr)
+			if err != nil {
+				return retval, err
+			}
+			retval.releases[name] = parseOSRelease(buf)
+			continue
+		}
+
+		parse := parserFor(name)
+		if parse == nil && hdr.Mode&0111 != 0 && hdr.Size > int64(len(elfMagic)) && hdr.Size < maxBinary {
+			head := make([]byte, len(elfMagic))
+			if _, err := io.ReadFull(tr, head); err != nil {
+				return retval, err
+			}
+			if !bytes.Equal(head, elfMagic) {
+				continue
+			}
+			buf, err := io.ReadAll(tr)
+			if err != nil {
+				return retval, err
+			}
+			// most executables are not Go, that is not an error, but they
+			// still replace whatever the lower layers had at this path
+			pkgs, _ := parseGoBinary(append(head, buf...))
+			retval.found[name] = pkgs
+			continue
+		}
+		if parse == nil || hdr.Size >= maxBinary {
+			continue
+		}
+
+		buf, err := io.ReadAll(tr)
+		if err != nil {
+			return retval, err
+		}
+		pkgs, err := parse(buf)
+		if err != nil {
+			logger.Warn("docker.sbom.parse:", name, " - ", err)
+		}
+		retval.found[name] = pkgs
+	}
+
+	return retval, nil
+}
+
+// Results lists the packages of the inventory.
+func (s SBOM) Results() Results {
+	retval := Results{
+		[]string{"Type", "Name", "Version", "Arch", "License", "Location"},
+		[][]string{},
+		[]int{0, 0, 0, 0, 0, 0},
+	}
+
+	for _, pkg := range s.Packages {
+		row := []string{
+			string(pkg.Type),
+			pkg.Name,
+			pkg.Version,
+			pkg.Arch,
+			pkg.License,
+			pkg.Location,
+		}
+		retval.Data = append(retval.Data, row)
+
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval
+}
+
+// Export writes the inventory to path as SPDX 2.3 or CycloneDX 1.5 JSON.
+func (s SBOM) Export(format string, path string) error {
+	logger.Trace(format, path)
+
+	var doc any
+	switch strings.ToLower(format) {
+	case SBOMFormatSPDX:
+		doc = s.spdx()
+	case SBOMFormatCycloneDX:
+		doc = s.cycloneDX()
+	default:
+		return errors.New("invalid format: [" + format + "]")
+	}
+
+	buf, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(buf, '\n'), 0644)
+}
+
+func (s SBOM) name() string {
+	if s.Reference != "" {
+		return s.Reference
+	}
+	return s.ID
+}
+
+// license expressions we can pass through to SPDX as they are, anything
+// else is noted in a comment
+var spdxLicense = regexp.MustCompile(`^[A-Za-z0-9.+-]+( (AND|OR|WITH) [A-Za-z0-9.+-]+)*$`)
+
+func (s SBOM) spdx() map[string]any {
+	image := map[string]any{
+		"name":                  s.name(),
+		"SPDXID":                "SPDXRef-Image",
+		"versionInfo":           s.ID,
+		"downloadLocation":      "NOASSERTION",
+		"filesAnalyzed":         false,
+		"primaryPackagePurpose": "CONTAINER",
+	}
+	packages := []map[string]any{image}
+	relationships := []map[string]any{
+		{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Image"},
+	}
+
+	for i, pkg := range s.Packages {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		entry := map[string]any{
+			"name":             pkg.Name,
+			"SPDXID":           id,
+			"versionInfo":      pkg.Version,
+			"downloadLocation": "NOASSERTION",
+			"filesAnalyzed":    false,
+			"licenseConcluded": "NOASSERTION",
+			"licenseDeclared":  "NOASSERTION",
+			"sourceInfo":       "found in " + pkg.Location,
+			"externalRefs": []map[string]any{
+				{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": pkg.PURL},
+			},
+		}
+		if pkg.License != "" {
+			if spdxLicense.MatchString(pkg.License) {
+				entry["licenseDeclared"] = pkg.License
+			} else {
+				entry["comment"] = "license: " + pkg.License
+			}
+		}
+		packages = append(packages, entry)
+		relationships = append(relationships, map[string]any{
+			"spdxElementId": "SPDXRef-Image", "relationshipType": "CONTAINS", "relatedSpdxElement": id,
+		})
+	}
+
+	return map[string]any{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              s.name(),
+		"documentNamespace": "https://github.com/presselam/yadc/spdx/" + purlEscape(s.name()) + "-" + uuid(),
+		"creationInfo": map[string]any{
+			"created":  time.Now().UTC().Format(time.RFC3339),
+			"creators": []string{"Tool: yadc"},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	}
+}
+
+func (s SBOM) cycloneDX() map[string]any {
+	var components []map[string]any
+	if s.Distro.ID != "" {
+		components = append(components, map[string]any{
+			"type":        "operating-system",
+			"bom-ref":     "os",
+			"name":        s.Distro.ID,
+			"version":     s.Distro.VersionID,
+			"description": s.Distro.Name,
+		})
+	}
+
+	for i, pkg := range s.Packages {
+		entry := map[string]any{
+			"type":    "library",
+			"bom-ref": fmt.Sprintf("pkg-%d", i+1),
+			"name":    pkg.Name,
+			"version": pkg.Version,
+			"purl":    pkg.PURL,
+			"properties": []map[string]string{
+				{"name": "yadc:location", "value": pkg.Location},
+			},
+		}
+		if pkg.License != "" {
+			entry["licenses"] = []map[string]any{
+				{"license": map[string]string{"name": pkg.License}},
+			}
+		}
+		components = append(components, entry)
+	}
+
+	return map[string]any{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.5",
+		"serialNumber": "urn:uuid:" + uuid(),
+		"version":      1,
+		"metadata": map[string]any{
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"tools": map[string]any{
+				"components": []map[string]string{{"type": "application", "name": "yadc"}},
+			},
+			"component": map[string]string{
+				"type":    "container",
+				"bom-ref": "image",
+				"name":    s.name(),
+				"version": s.ID,
+			},
+		},
+		"components": components,
+	}
+}
+
+// uuid returns a random version 4 UUID.
+func uuid() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
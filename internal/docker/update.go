@@ -0,0 +1,207 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"github.com/presselam/yadc/internal/logger"
+	"strconv"
+	"strings"
+)
+
+// UpdateSpec holds the settings of a live container that the engine allows
+// to change in place, formatted for editing.
+type UpdateSpec struct {
+	Name       string
+	CPUShares  string
+	CPUQuota   string
+	Memory     string
+	MemorySwap string
+	PidsLimit  string
+	Restart    string
+}
+
+// the weight the engine gives containers that set none
+const defaultCPUShares = 1024
+
+// ContainerUpdateSpec reads the current settings of a container.
+func ContainerUpdateSpec(id string) (UpdateSpec, error) {
+	logger.Trace(id)
+
+	docker, err := newClient()
+	if err != nil {
+		return UpdateSpec{}, err
+	}
+	defer docker.Close()
+
+	inspect, err := docker.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return UpdateSpec{}, err
+	}
+
+	return updateSpecOf(inspect), nil
+}
+
+func updateSpecOf(inspect container.InspectResponse) UpdateSpec {
+	var retval UpdateSpec
+
+	host := inspect.HostConfig
+	retval.Name = strings.TrimPrefix(inspect.Name, "/")
+	retval.CPUShares = formatLimit(host.CPUShares)
+	retval.CPUQuota = formatLimit(host.CPUQuota)
+	retval.Memory = formatMemory(host.Memory)
+	retval.MemorySwap = formatMemory(host.MemorySwap)
+	if host.PidsLimit != nil {
+		retval.PidsLimit = formatLimit(*host.PidsLimit)
+	}
+	retval.Restart = formatRestartPolicy(host.RestartPolicy)
+
+	return retval
+}
+
+// ContainerUpdate applies the settings of spec that differ from those of
+// the running container, renaming it when the name changed.
+func ContainerUpdate(id string, spec UpdateSpec) error {
+	logger.Trace(id)
+
+	docker, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer docker.Close()
+
+	inspect, err := docker.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return err
+	}
+
+	update, err := spec.changes(updateSpecOf(inspect))
+	if err != nil {
+		return err
+	}
+
+	response, err := docker.ContainerUpdate(context.Background(), id, update)
+	if err != nil {
+		return err
+	}
+	for _, warning := range response.Warnings {
+		logger.Warn("docker.update:", warning)
+	}
+
+	if spec.Name != "" && spec.Name != strings.TrimPrefix(inspect.Name, "/") {
+		err = docker.ContainerRename(context.Background(), id, spec.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changes builds the update for the fields of spec edited away from
+// current. The engine leaving zero values alone, an emptied field asks for
+// no limit, or the default, instead.
+func (spec UpdateSpec) changes(current UpdateSpec) (container.UpdateConfig, error) {
+	update := container.UpdateConfig{}
+	var err error
+	if spec.CPUShares != current.CPUShares {
+		if update.CPUShares, err = parseLimit("cpu shares", spec.CPUShares, defaultCPUShares); err != nil {
+			return update, err
+		}
+	}
+	if spec.CPUQuota != current.CPUQuota {
+		if update.CPUQuota, err = parseLimit("cpu quota", spec.CPUQuota, -1); err != nil {
+			return update, err
+		}
+	}
+	if spec.Memory != current.Memory {
+		if update.Memory, err = parseMemory(spec.Memory); err != nil {
+			return update, err
+		}
+	}
+	if spec.MemorySwap != current.MemorySwap {
+		if update.MemorySwap, err = parseMemory(spec.MemorySwap); err != nil {
+			return update, err
+		}
+	}
+	if spec.PidsLimit != current.PidsLimit {
+		pids, err := parseLimit("pids limit", spec.PidsLimit, -1)
+		if err != nil {
+			return update, err
+		}
+		update.PidsLimit = &pids
+	}
+	if spec.Restart != current.Restart {
+		if update.RestartPolicy, err = ParseRestartPolicy(spec.Restart); err != nil {
+			return update, err
+		}
+		if update.RestartPolicy.Name == "" {
+			update.RestartPolicy.Name = container.RestartPolicyDisabled
+		}
+	}
+	return update, nil
+}
+
+func formatLimit(value int64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// parseLimit reads a whole number, unset standing in for an empty value.
+func parseLimit(name string, value string, unset int64) (int64, error) {
+	if value == "" {
+		return unset, nil
+	}
+	retval, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid " + name + ": [" + value + "]")
+	}
+	return retval, nil
+}
+
+// memoryUnits are the units memory is written in, largest first.
+var memoryUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"g", units.GiB},
+	{"m", units.MiB},
+	{"k", units.KiB},
+}
+
+// formatMemory writes value in the largest unit that holds it exactly, so
+// it reads back as the same number of bytes.
+func formatMemory(value int64) string {
+	switch {
+	case value == 0:
+		return ""
+	case value < 0:
+		return "-1"
+	}
+	for _, unit := range memoryUnits {
+		if value%unit.size == 0 {
+			return strconv.FormatInt(value/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// parseMemory reads a size such as 512m, empty meaning unlimited.
+func parseMemory(value string) (int64, error) {
+	switch value {
+	case "", "-1":
+		return -1, nil
+	}
+	return units.RAMInBytes(value)
+}
+
+func formatRestartPolicy(policy container.RestartPolicy) string {
+	retval := string(policy.Name)
+	if policy.IsOnFailure() && policy.MaximumRetryCount > 0 {
+		retval += ":" + strconv.Itoa(policy.MaximumRetryCount)
+	}
+	return retval
+}
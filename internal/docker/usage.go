@@ -0,0 +1,260 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/presselam/yadc/internal/logger"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const UsageTotal = "*"
+
+// ImageUsage is the disk use of one image. Size is what docker images
+// reports, Unique the bytes no other image references, which is what
+// removing the image alone would free.
+type ImageUsage struct {
+	ID         string
+	Name       string
+	Containers int64
+	Layers     []string
+	Size       int64
+	Unique     int64
+	Shared     int64
+	Base       string
+	BaseSize   int64
+}
+
+// UsageLayer is a layer on disk: identified by its chain ID, as the same
+// diff on top of a different parent is stored again.
+type UsageLayer struct {
+	ChainID string
+	Size    int64
+	Images  []string
+}
+
+type DiskUsage struct {
+	Images []ImageUsage
+	Layers map[string]*UsageLayer
+	Size   int64
+	Actual int64
+}
+
+type layerSize struct {
+	chainID string
+	size    int64
+}
+
+// imageLayers caches the layers of every image by ID, they never change.
+var imageLayers sync.Map
+
+// ImageDiskUsage works out how much of every image is shared with other
+// images from the layer chains, and groups the images by the deepest base
+// they have in common with another image.
+func ImageDiskUsage() (DiskUsage, error) {
+	logger.Trace()
+	retval := DiskUsage{Layers: make(map[string]*UsageLayer)}
+
+	docker, err := newClient()
+	if err != nil {
+		return retval, err
+	}
+	defer docker.Close()
+
+	images, err := docker.ImageList(context.Background(), image.ListOptions{All: true})
+	if err != nil {
+		return retval, err
+	}
+
+	for _, img := range images {
+		layers, err := layersOf(docker, img.ID)
+		if err != nil {
+			logger.Warn("docker.usage.layers:", img.ID, " - ", err)
+			continue
+		}
+
+		usage := ImageUsage{
+			ID:         ShortID(img.ID),
+			Name:       imageNone,
+			Containers: img.Containers,
+			Size:       img.Size,
+		}
+		if len(img.RepoTags) > 0 {
+			usage.Name = img.RepoTags[0]
+			if len(img.RepoTags) > 1 {
+				usage.Name += fmt.Sprintf(" (+%d)", len(img.RepoTags)-1)
+			}
+		}
+
+		for _, layer := range layers {
+			usage.Layers = append(usage.Layers, layer.chainID)
+			entry, ok := retval.Layers[layer.chainID]
+			if !ok {
+				entry = &UsageLayer{ChainID: layer.chainID, Size: layer.size}
+				retval.Layers[layer.chainID] = entry
+				retval.Actual += layer.size
+			}
+			entry.Images = append(entry.Images, usage.ID)
+		}
+
+		retval.Images = append(retval.Images, usage)
+		retval.Size += img.Size
+	}
+
+	for i := range retval.Images {
+		usage := &retval.Images[i]
+		usage.Unique = retval.Freed([]string{usage.ID})
+		usage.Shared = usage.Size - usage.Unique
+
+		// layers are stacked, so the deepest shared one is the base
+		for _, chainID := range usage.Layers {
+			layer := retval.Layers[chainID]
+			if len(layer.Images) < 2 {
+				break
+			}
+			usage.Base = chainID
+			usage.BaseSize += layer.Size
+		}
+	}
+
+	sort.SliceStable(retval.Images, func(i, j int) bool {
+		a, b := retval.Images[i], retval.Images[j]
+		if (a.Base == "") != (b.Base == "") {
+			return a.Base != ""
+		}
+		if a.Base != b.Base {
+			return a.Base < b.Base
+		}
+		return a.Name < b.Name
+	})
+
+	return retval, nil
+}
+
+// layersOf pairs the layers of an image with their sizes. The history has
+// one entry per instruction, newest first, and only the entries that did
+// not leave an empty layer carry a size, so sized entries are matched to
+// layers in order and unsized ones only when there are layers left over.
+func layersOf(docker *client.Client, id string) ([]layerSize, error) {
+	if val, ok := imageLayers.Load(id); ok {
+		return val.([]layerSize), nil
+	}
+
+	inspect, err := docker.ImageInspect(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	history, err := docker.ImageHistory(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(history)
+
+	diffs := inspect.RootFS.Layers
+	sized := 0
+	for _, h := range history {
+		if h.Size > 0 {
+			sized++
+		}
+	}
+
+	var retval []layerSize
+	chainID := ""
+	for _, h := range history {
+		if len(retval) == len(diffs) {
+			break
+		}
+		if h.Size > 0 {
+			sized--
+		} else if len(diffs)-len(retval) <= sized {
+			continue
+		}
+
+		diff := diffs[len(retval)]
+		if chainID == "" {
+			chainID = diff
+		} else {
+			sum := sha256.Sum256([]byte(chainID + " " + diff))
+			chainID = shaPrefix + hex.EncodeToString(sum[:])
+		}
+		retval = append(retval, layerSize{chainID: chainID, size: h.Size})
+	}
+
+	imageLayers.Store(id, retval)
+	return retval, nil
+}
+
+// Freed is the number of bytes removing all of ids would give back: the
+// layers no other image references.
+func (u DiskUsage) Freed(ids []string) int64 {
+	var retval int64
+	for _, layer := range u.Layers {
+		freed := true
+		for _, id := range layer.Images {
+			if !slices.Contains(ids, id) {
+				freed = false
+				break
+			}
+		}
+		if freed {
+			retval += layer.Size
+		}
+	}
+	return retval
+}
+
+// Results lists the images by base, marking those in selected, with a
+// summary row on top giving what removing the selection would free.
+func (u DiskUsage) Results(selected []string) Results {
+	retval := Results{
+		[]string{"ID", "Name", "Containers", "Size", "Unique", "Shared", "Base", "Selected"},
+		[][]string{},
+		[]int{0, 0, 0, 0, 0, 0, 0, 0},
+	}
+
+	summary := fmt.Sprintf("on disk: %d  saved by sharing: %d", u.Actual, u.Size-u.Actual)
+	if len(selected) > 0 {
+		summary = fmt.Sprintf("removing %d selected frees %d", len(selected), u.Freed(selected))
+	}
+	rows := [][]string{
+		{UsageTotal, summary, "", strconv.FormatInt(u.Size, 10), strconv.FormatInt(u.Actual, 10), strconv.FormatInt(u.Size-u.Actual, 10), "", ""},
+	}
+
+	for _, img := range u.Images {
+		base := ""
+		if img.Base != "" {
+			base = fmt.Sprintf("%s (%d)", ShortID(img.Base), img.BaseSize)
+		}
+		mark := ""
+		if slices.Contains(selected, img.ID) {
+			mark = "*"
+		}
+		rows = append(rows, []string{
+			img.ID,
+			img.Name,
+			strconv.FormatInt(img.Containers, 10),
+			strconv.FormatInt(img.Size, 10),
+			strconv.FormatInt(img.Unique, 10),
+			strconv.FormatInt(img.Shared, 10),
+			base,
+			mark,
+		})
+	}
+
+	for _, row := range rows {
+		retval.Data = append(retval.Data, row)
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval
+}
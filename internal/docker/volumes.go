@@ -0,0 +1,350 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/presselam/yadc/internal/logger"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultHelperImage = "busybox:latest"
+
+	helperMount    = "/volume"
+	helperLabel    = "yadc.helper"
+	checksumSuffix = ".sha256"
+
+	// a helper only lives as long as a backup or a restore, one older than
+	// this was left behind by a run that did not get to remove it
+	helperMaxAge = 24 * time.Hour
+)
+
+var helperImage = DefaultHelperImage
+
+// SetHelperImage sets the image of the short lived containers that give
+// access to the contents of a volume. It only has to exist, it never runs.
+func SetHelperImage(ref string) {
+	if ref == "" {
+		ref = DefaultHelperImage
+	}
+	helperImage = ref
+}
+
+func Volumes() (Results, error) {
+	logger.Trace()
+	retval := Results{
+		[]string{"Name", "Driver", "Scope", "Created", "Mountpoint", "Labels"},
+		[][]string{},
+		[]int{0, 0, 0, 0, 0, 0},
+	}
+
+	docker, err := newClient()
+	if err != nil {
+		return retval, err
+	}
+	defer docker.Close()
+
+	response, err := docker.VolumeList(context.Background(), volume.ListOptions{})
+	if err != nil {
+		return retval, err
+	}
+
+	for _, vol := range response.Volumes {
+		row := []string{
+			vol.Name,
+			vol.Driver,
+			vol.Scope,
+			vol.CreatedAt,
+			vol.Mountpoint,
+			displayLabels(vol.Labels),
+		}
+		retval.Data = append(retval.Data, row)
+
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval, nil
+}
+
+// createHelper creates, without starting it, a container with the volume
+// mounted, pulling the helper image first if needed. The caller removes it.
+func createHelper(docker *client.Client, name string, readOnly bool) (string, error) {
+	ctx := context.Background()
+	if _, err := docker.ImageInspect(ctx, helperImage); client.IsErrNotFound(err) {
+		if _, err := ImagePull(helperImage, registry.AuthConfig{}); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	config := &container.Config{
+		Image:  helperImage,
+		Cmd:    []string{"true"},
+		Labels: map[string]string{helperLabel: "true"},
+	}
+	host := &container.HostConfig{
+		Mounts: []mount.Mount{
+			{Type: mount.TypeVolume, Source: name, Target: helperMount, ReadOnly: readOnly},
+		},
+	}
+
+	created, err := docker.ContainerCreate(ctx, config, host, nil, nil, "")
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func removeHelper(docker *client.Client, id string) {
+	err := docker.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
+	if err != nil {
+		logger.Error("docker.volumes.removeHelper:", err)
+	}
+}
+
+// ReapHelpers removes the helper containers left behind by earlier runs,
+// found by their label. Recent ones may belong to another instance still
+// using them and are left alone.
+func ReapHelpers() error {
+	logger.Trace()
+
+	docker, err := newClient()
+	if err != nil {
+		return err
+	}
+	defer docker.Close()
+
+	options := container.ListOptions{All: true, Filters: filters.NewArgs(filters.Arg("label", helperLabel))}
+	helpers, err := docker.ContainerList(context.Background(), options)
+	if err != nil {
+		return err
+	}
+
+	for _, helper := range helpers {
+		if time.Since(time.Unix(helper.Created, 0)) < helperMaxAge {
+			continue
+		}
+		removeHelper(docker, helper.ID)
+		logger.Info("Removed leftover helper: ", helper.ID)
+	}
+	return nil
+}
+
+// VolumeBackup copies the contents of a volume into a gzipped tarball at
+// file, with paths relative to the root of the volume, and writes its
+// checksum next to it. It returns the checksum and the bytes read from the
+// volume.
+func VolumeBackup(name string, file string, progress func(int64)) (string, int64, error) {
+	logger.Trace(name, file)
+
+	docker, err := newClient()
+	if err != nil {
+		return "", 0, err
+	}
+	defer docker.Close()
+
+	helper, err := createHelper(docker, name, true)
+	if err != nil {
+		return "", 0, err
+	}
+	defer removeHelper(docker, helper)
+
+	reader, _, err := docker.CopyFromContainer(context.Background(), helper, helperMount)
+	if err != nil {
+		return "", 0, err
+	}
+	defer reader.Close()
+
+	partial := file + partialSuffix
+	f, err := os.Create(partial)
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(partial)
+	defer f.Close()
+
+	hash := sha256.New()
+	buffered := bufio.NewWriter(io.MultiWriter(f, hash))
+	gz := gzip.NewWriter(buffered)
+
+	counter := &progressWriter{w: io.Discard, progress: progress}
+	err = rebase(tar.NewReader(io.TeeReader(reader, counter)), tar.NewWriter(gz))
+	if err != nil {
+		return "", counter.written, err
+	}
+	if err := gz.Close(); err != nil {
+		return "", counter.written, err
+	}
+	if err := buffered.Flush(); err != nil {
+		return "", counter.written, err
+	}
+	if err := f.Close(); err != nil {
+		return "", counter.written, err
+	}
+	if progress != nil {
+		progress(counter.written)
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if err := os.Rename(partial, file); err != nil {
+		return "", counter.written, err
+	}
+
+	line := fmt.Sprintf("%s  %s\n", checksum, filepath.Base(file))
+	if err := os.WriteFile(file+checksumSuffix, []byte(line), 0644); err != nil {
+		return checksum, counter.written, err
+	}
+
+	logger.Info("Backed up: ", name, " => ", file)
+	return checksum, counter.written, nil
+}
+
+// rebase rewrites the archive of the mount point so the entries are
+// relative to the root of the volume, like tar -C volume . would make them.
+func rebase(tr *tar.Reader, tw *tar.Writer) error {
+	prefix := path.Base(helperMount)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = rebaseName(prefix, hdr.Name)
+		if hdr.Typeflag == tar.TypeLink {
+			hdr.Linkname = rebaseName(prefix, hdr.Linkname)
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func rebaseName(prefix string, name string) string {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, prefix), "/")
+	return "./" + name
+}
+
+// VolumeRestore extracts a tarball, gzipped or not, into a volume, creating
+// the volume when it does not exist. Existing files with the same names are
+// overwritten, others are left alone. When the backup has a checksum file
+// the tarball is verified before anything is written.
+func VolumeRestore(file string, name string, progress func(int64)) (int64, error) {
+	logger.Trace(file, name)
+
+	if buf, err := os.ReadFile(file + checksumSuffix); err == nil {
+		fields := strings.Fields(string(buf))
+		if len(fields) == 0 {
+			return 0, errors.New("empty checksum file: [" + file + checksumSuffix + "]")
+		}
+		if err := verifyChecksum(file, fields[0]); err != nil {
+			return 0, err
+		}
+	} else if errors.Is(err, os.ErrNotExist) {
+		logger.Warn("docker.volumes.restore: no checksum for ", file)
+	} else {
+		return 0, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// plain tarballs are fine too
+	br := bufio.NewReader(f)
+	var archive io.Reader = br
+	if head, _ := br.Peek(2); bytes.Equal(head, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		archive = gz
+	}
+
+	docker, err := newClient()
+	if err != nil {
+		return 0, err
+	}
+	defer docker.Close()
+
+	_, err = docker.VolumeInspect(context.Background(), name)
+	if client.IsErrNotFound(err) {
+		_, err = docker.VolumeCreate(context.Background(), volume.CreateOptions{Name: name})
+		if err == nil {
+			logger.Info("Created volume: ", name)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	helper, err := createHelper(docker, name, false)
+	if err != nil {
+		return 0, err
+	}
+	defer removeHelper(docker, helper)
+
+	counter := &progressWriter{w: io.Discard, progress: progress}
+	err = docker.CopyToContainer(context.Background(), helper, helperMount, io.TeeReader(archive, counter), container.CopyToContainerOptions{})
+	if err != nil {
+		return counter.written, err
+	}
+	if progress != nil {
+		progress(counter.written)
+	}
+
+	logger.Info("Restored: ", file, " => ", name)
+	return counter.written, nil
+}
+
+func verifyChecksum(file string, expected string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", file, expected, actual)
+	}
+	return nil
+}
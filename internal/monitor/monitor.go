@@ -8,6 +8,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/presselam/yadc/internal/banner"
 	"github.com/presselam/yadc/internal/dialog"
+	"github.com/presselam/yadc/internal/docker"
 	"github.com/presselam/yadc/internal/logger"
 	"github.com/presselam/yadc/internal/table"
 	"github.com/presselam/yadc/internal/timers"
@@ -27,6 +28,9 @@ const (
 	ContainerMode              = ":containers"
 	ImageMode                  = ":images"
 	VolumeMode                 = ":volumes"
+	RegistryMode               = ":registry"
+	GraphMode                  = ":graph"
+	MouseCommand               = ":mouse"
 )
 
 var (
@@ -49,6 +53,15 @@ type model struct {
 	height int
 	mode   string
 	dialog dialog.Model
+	mouse  bool
+}
+
+// mouse is whether the monitor starts out taking mouse events, which
+// keeps the terminal from selecting text
+var mouse = true
+
+func SetMouse(enabled bool) {
+	mouse = enabled
 }
 
 var spinnerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("69"))
@@ -78,6 +91,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case inputFocus:
 			switch {
+			case key.Matches(msg, KeyEnter) && strings.TrimSpace(m.input.Value()) == MouseCommand:
+				m.state = tableFocus
+				m.input.SetValue("")
+				m.input.Prompt = ""
+				return m, m.toggleMouse()
 			case key.Matches(msg, KeyEnter):
 				m.state = tableFocus
 				err := m.setContext(m.input.Value())
@@ -91,23 +109,29 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.input.SetValue("")
 				m.input.Prompt = ""
+				// the enter was the command's, not the table's
+				return m, m.table.Pending()
 			}
 		case tableFocus:
 			switch {
 			case key.Matches(msg, KeyQuit):
 				return m, tea.Quit
 			case key.Matches(msg, KeyCommand):
-				if m.table.Focus() != table.DialogFocus {
+				if m.table.Focus() == table.TableFocus {
 					m.state = inputFocus
 					m.input.Prompt = "!"
 					m.input.SetValue("")
 					m.input.Focus()
 				}
-			case key.Matches(msg, KeyEscape):
+			case key.Matches(msg, KeyEscape) && m.table.Focus() == table.TableFocus:
 				err := m.setContext(m.mode)
 				if err != nil {
 					log.Printf("Context Error: [%v]", err)
 				}
+				// going back to the registry reads it again
+				if m.table.Focus() == table.DialogFocus {
+					return m, m.table.Pending()
+				}
 			}
 		}
 
@@ -116,10 +140,34 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tableFocus:
 			m.table, cmd = m.table.Update(msg)
 			cmds = append(cmds, cmd)
+			m.syncMode()
 		case inputFocus:
 			m.input, cmd = m.input.Update(msg)
 			cmds = append(cmds, cmd)
 		}
+	case tea.MouseMsg:
+		switch m.state {
+		case dialogFocus:
+			if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+				x, y := m.dialogOrigin()
+				if button := m.dialog.ButtonAt(msg.X-x, msg.Y-y); button >= 0 {
+					m.dialog.Click(button)
+					m.state = tableFocus
+				}
+			}
+		case tableFocus:
+			// the table sits under the banner
+			msg.Y -= lipgloss.Height(m.banner.View())
+			m.table, cmd = m.table.Update(msg)
+			cmds = append(cmds, cmd)
+			m.syncMode()
+		}
+	case docker.ConnectionMsg:
+		m.table, cmd = m.table.Update(msg)
+		cmds = append(cmds, cmd)
+	case banner.StatusMsg:
+		m.banner, cmd = m.banner.Update(msg)
+		cmds = append(cmds, cmd)
 	case timers.TimerMsg:
 		m.banner, cmd = m.banner.Update(msg)
 		cmds = append(cmds, cmd)
@@ -132,6 +180,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 		m.width = msg.Width
 		m.height = msg.Height
+	default:
+		// results of commands the table started
+		m.table, cmd = m.table.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 
 	return m, tea.Batch(cmds...)
@@ -139,13 +191,28 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m *model) setContext(name string) error {
 	logger.Trace(name)
-	switch {
-	case strings.HasPrefix(ContainerMode, name):
+	args := strings.Fields(name)
+	if len(args) == 0 {
+		return errors.New("Unsupported Command: [" + name + "]")
+	}
+
+	switch command := args[0]; {
+	case strings.HasPrefix(ContainerMode, command):
 		m.table.SetContext(table.ContainerContext)
-	case strings.HasPrefix(ImageMode, name):
+	case strings.HasPrefix(ImageMode, command):
 		m.table.SetContext(table.ImageContext)
-	case strings.HasPrefix(VolumeMode, name):
+	case strings.HasPrefix(VolumeMode, command):
 		m.table.SetContext(table.VolumeContext)
+	case strings.HasPrefix(RegistryMode, command):
+		if len(args) != 2 {
+			return errors.New("Usage: " + RegistryMode + " <host>")
+		}
+		m.table.SetRegistry(args[1])
+	case strings.HasPrefix(GraphMode, command):
+		err := m.table.ShowGraph()
+		if err != nil {
+			return err
+		}
 	default:
 		return errors.New("Unsupported Command: [" + name + "]")
 	}
@@ -153,8 +220,58 @@ func (m *model) setContext(name string) error {
 	return nil
 }
 
+// syncMode follows the table when an action switches it to another mode,
+// so escape returns there.
+func (m *model) syncMode() {
+	switch m.table.Context() {
+	case table.ContainerContext:
+		if !strings.HasPrefix(ContainerMode, m.mode) {
+			m.mode = ContainerMode
+		}
+	case table.ImageContext:
+		if !strings.HasPrefix(ImageMode, m.mode) {
+			m.mode = ImageMode
+		}
+	case table.VolumeContext:
+		if !strings.HasPrefix(VolumeMode, m.mode) {
+			m.mode = VolumeMode
+		}
+	}
+}
+
+// toggleMouse turns mouse events on or off, off letting the terminal
+// select text again.
+func (m *model) toggleMouse() tea.Cmd {
+	m.mouse = !m.mouse
+	if m.mouse {
+		return tea.EnableMouseCellMotion
+	}
+	return tea.DisableMouse
+}
+
 func (m model) View() string {
 	logger.Trace()
+	s := m.screen()
+
+	if m.state == dialogFocus {
+		x, y := m.dialogOrigin()
+		return dialog.PlaceOverlay(x, y, m.dialog.ConfirmDialog(), s, false)
+	}
+
+	return s
+}
+
+// dialogOrigin is where the top left corner of the dialog goes, in the
+// middle of the screen.
+func (m model) dialogOrigin() (int, int) {
+	s := m.screen()
+	popup := m.dialog.ConfirmDialog()
+	return lipgloss.Width(s)/2 - lipgloss.Width(popup)/2,
+		lipgloss.Height(s)/2 - lipgloss.Height(popup)/2
+}
+
+// screen renders the banner, the table and the command input.
+func (m model) screen() string {
 	var s string
 
 	banner := lipgloss.NewStyle().
@@ -183,18 +300,6 @@ func (m model) View() string {
 		input,
 	)
 
-	if m.state == dialogFocus {
-		popup := m.dialog.ConfirmDialog()
-
-		return dialog.PlaceOverlay(
-			lipgloss.Width(s)/2-lipgloss.Width(popup)/2,
-			lipgloss.Height(s)/2-lipgloss.Height(popup)/2,
-			popup,
-			s,
-			false,
-		)
-	}
-
 	return s
 }
 
@@ -203,14 +308,18 @@ func Show(mode string) {
 	logger.Setup()
 	logger.StartBanner()
 
-	m := model{state: tableFocus}
+	m := model{state: tableFocus, mouse: mouse}
 	m.banner = banner.New()
 	m.table = table.New()
 	m.input = textinput.New()
 	m.input.Prompt = ""
 	m.setContext(mode)
 
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if m.mouse {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(m, opts...)
 
 	if _, err := p.Run(); err != nil {
 		log.Fatal(err)
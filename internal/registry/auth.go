@@ -0,0 +1,119 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	regtypes "github.com/docker/docker/api/types/registry"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dockerConfig is the part of the docker CLI config file that carries
+// registry credentials.
+type dockerConfig struct {
+	Auths       map[string]regtypes.AuthConfig `json:"auths"`
+	CredsStore  string                         `json:"credsStore"`
+	CredHelpers map[string]string              `json:"credHelpers"`
+}
+
+func configPath() string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+// LoadAuth looks up the credentials for host the same way the docker CLI
+// does: a per-registry credential helper, then the default credential store,
+// then the inline auths of the config file.
+func LoadAuth(host string) (regtypes.AuthConfig, error) {
+	retval := regtypes.AuthConfig{ServerAddress: host}
+
+	buf, err := os.ReadFile(configPath())
+	if os.IsNotExist(err) {
+		return retval, nil
+	}
+	if err != nil {
+		return retval, err
+	}
+
+	var config dockerConfig
+	if err := json.Unmarshal(buf, &config); err != nil {
+		return retval, err
+	}
+
+	helper := config.CredHelpers[host]
+	if helper == "" {
+		helper = config.CredsStore
+	}
+	if helper != "" {
+		if auth, err := helperAuth(helper, host); err == nil {
+			return auth, nil
+		}
+	}
+
+	for name, auth := range config.Auths {
+		if normalizeHost(name) != host {
+			continue
+		}
+		if auth.Auth != "" && auth.Username == "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return retval, err
+			}
+			user, pass, _ := strings.Cut(string(decoded), ":")
+			auth.Username = user
+			auth.Password = pass
+		}
+		auth.ServerAddress = host
+		return auth, nil
+	}
+
+	return retval, nil
+}
+
+func helperAuth(helper string, host string) (regtypes.AuthConfig, error) {
+	retval := regtypes.AuthConfig{ServerAddress: host}
+
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(host)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return retval, err
+	}
+
+	var creds struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(out.Bytes(), &creds); err != nil {
+		return retval, err
+	}
+
+	// helpers hand back identity tokens with the magic "<token>" user name
+	if creds.Username == "<token>" {
+		retval.IdentityToken = creds.Secret
+	} else {
+		retval.Username = creds.Username
+		retval.Password = creds.Secret
+	}
+	return retval, nil
+}
+
+// normalizeHost strips the scheme and path config entries sometimes carry,
+// e.g. "https://index.docker.io/v1/".
+func normalizeHost(name string) string {
+	name = strings.TrimPrefix(name, "https://")
+	name = strings.TrimPrefix(name, "http://")
+	host, _, _ := strings.Cut(name, "/")
+	return host
+}
@@ -0,0 +1,477 @@
+// Package registry browses a container registry over the Registry HTTP
+// API v2.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	regtypes "github.com/docker/docker/api/types/registry"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/presselam/yadc/internal/docker"
+	"github.com/presselam/yadc/internal/logger"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	pageSize = 100
+
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	attestationType         = "vnd.docker.reference.type"
+)
+
+var (
+	manifestTypes = []string{
+		ocispec.MediaTypeImageIndex,
+		ocispec.MediaTypeImageManifest,
+		mediaTypeDockerList,
+		mediaTypeDockerManifest,
+	}
+	nextLink  = regexp.MustCompile(`<([^>]+)>;\s*rel="?next"?`)
+	challenge = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+// Client talks to a single registry. Credentials come from the docker
+// config file unless supplied with WithAuth.
+type Client struct {
+	host  string
+	base  *url.URL
+	http  *http.Client
+	auth  regtypes.AuthConfig
+	token string
+	basic bool
+}
+
+// Option is used to set options in New.
+type Option func(*Client)
+
+// WithHTTPClient sets the http client used for every request, e.g. one
+// pointing at a test server.
+func WithHTTPClient(c *http.Client) Option {
+	return func(r *Client) {
+		r.http = c
+	}
+}
+
+// WithAuth overrides the credentials read from the docker config file.
+func WithAuth(auth regtypes.AuthConfig) Option {
+	return func(r *Client) {
+		r.auth = auth
+	}
+}
+
+// New creates a client for host, which may carry an explicit scheme.
+// Without one https is used, except for loopback registries which are
+// spoken to over plain http like the engine does.
+func New(host string, opts ...Option) (*Client, error) {
+	logger.Trace(host)
+
+	if !strings.Contains(host, "://") {
+		scheme := "https"
+		if isLoopback(host) {
+			scheme = "http"
+		}
+		host = scheme + "://" + host
+	}
+	base, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+	if base.Host == "" {
+		return nil, errors.New("registry host missing: [" + host + "]")
+	}
+
+	r := &Client{
+		host: base.Host,
+		base: base,
+		http: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	auth, err := LoadAuth(base.Host)
+	if err != nil {
+		logger.Warn("registry.auth:", err)
+	}
+	r.auth = auth
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	resp, err := r.get("/v2/")
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return r, nil
+}
+
+// Host returns the registry host as used in image references.
+func (r *Client) Host() string { return r.host }
+
+// Auth returns the credentials used against the registry.
+func (r *Client) Auth() regtypes.AuthConfig { return r.auth }
+
+// Reference builds the image reference of a tag in this registry.
+func (r *Client) Reference(repo string, tag string) string {
+	return r.host + "/" + repo + ":" + tag
+}
+
+// Repositories pages through the whole catalog.
+func (r *Client) Repositories() (docker.Results, error) {
+	logger.Trace(r.host)
+	retval := docker.Results{
+		Columns: []string{"Repository"},
+		Data:    [][]string{},
+		Width:   []int{0},
+	}
+
+	var page struct {
+		Repositories []string `json:"repositories"`
+	}
+	next := "/v2/_catalog?n=" + strconv.Itoa(pageSize)
+	for next != "" {
+		page.Repositories = nil
+		link, err := r.getJSON(next, &page)
+		if err != nil {
+			return retval, err
+		}
+		for _, repo := range page.Repositories {
+			retval.Data = append(retval.Data, []string{repo})
+			retval.Width[0] = max(retval.Width[0], len(repo))
+		}
+		next = link
+	}
+
+	return retval, nil
+}
+
+// Tags pages through every tag of repo.
+func (r *Client) Tags(repo string) (docker.Results, error) {
+	logger.Trace(repo)
+	retval := docker.Results{
+		Columns: []string{"Tag", "Repository"},
+		Data:    [][]string{},
+		Width:   []int{0, len(repo)},
+	}
+
+	var page struct {
+		Tags []string `json:"tags"`
+	}
+	next := "/v2/" + repo + "/tags/list?n=" + strconv.Itoa(pageSize)
+	for next != "" {
+		page.Tags = nil
+		link, err := r.getJSON(next, &page)
+		if err != nil {
+			return retval, err
+		}
+		for _, tag := range page.Tags {
+			retval.Data = append(retval.Data, []string{tag, repo})
+			retval.Width[0] = max(retval.Width[0], len(tag))
+		}
+		next = link
+	}
+
+	return retval, nil
+}
+
+// Manifest lists the digest, size and platforms of a tag. A single
+// platform image yields one row, an index one row per platform.
+func (r *Client) Manifest(repo string, tag string) (docker.Results, error) {
+	logger.Trace(repo, tag)
+	retval := docker.Results{
+		Columns: []string{"Tag", "Platform", "Digest", "Media Type", "Size"},
+		Data:    [][]string{},
+		Width:   []int{0, 0, 0, 0, 0},
+	}
+
+	digest, mediaType, body, err := r.fetchManifest(repo, tag)
+	if err != nil {
+		return retval, err
+	}
+
+	var rows [][]string
+	switch mediaType {
+	case ocispec.MediaTypeImageIndex, mediaTypeDockerList:
+		var index ocispec.Index
+		if err := json.Unmarshal(body, &index); err != nil {
+			return retval, err
+		}
+
+		var total int64
+		for _, desc := range index.Manifests {
+			if desc.Annotations[attestationType] != "" {
+				continue
+			}
+			size, err := r.imageSize(repo, desc.Digest.String())
+			if err != nil {
+				return retval, err
+			}
+			total += size
+			rows = append(rows, []string{
+				"",
+				platformName(desc.Platform),
+				desc.Digest.String(),
+				desc.MediaType,
+				strconv.FormatInt(size, 10),
+			})
+		}
+		rows = append([][]string{{tag, "", digest, mediaType, strconv.FormatInt(total, 10)}}, rows...)
+	default:
+		size, err := manifestSize(body)
+		if err != nil {
+			return retval, err
+		}
+		rows = append(rows, []string{tag, "", digest, mediaType, strconv.FormatInt(size, 10)})
+	}
+
+	for _, row := range rows {
+		retval.Data = append(retval.Data, row)
+		for i, val := range row {
+			retval.Width[i] = max(retval.Width[i], len(val))
+		}
+	}
+
+	return retval, nil
+}
+
+func (r *Client) imageSize(repo string, digest string) (int64, error) {
+	_, _, body, err := r.fetchManifest(repo, digest)
+	if err != nil {
+		return 0, err
+	}
+	return manifestSize(body)
+}
+
+func (r *Client) fetchManifest(repo string, ref string) (string, string, []byte, error) {
+	resp, err := r.get("/v2/"+repo+"/manifests/"+ref, manifestTypes...)
+	if err != nil {
+		return "", "", nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	mediaType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	if mediaType == "" || mediaType == "application/json" {
+		var probe struct {
+			MediaType string `json:"mediaType"`
+		}
+		json.Unmarshal(body, &probe)
+		mediaType = probe.MediaType
+	}
+
+	return resp.Header.Get("Docker-Content-Digest"), mediaType, body, nil
+}
+
+func manifestSize(body []byte) (int64, error) {
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return 0, err
+	}
+
+	retval := manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		retval += layer.Size
+	}
+	return retval, nil
+}
+
+func platformName(p *ocispec.Platform) string {
+	if p == nil {
+		return ""
+	}
+	parts := []string{p.OS, p.Architecture}
+	if p.Variant != "" {
+		parts = append(parts, p.Variant)
+	}
+	return strings.Join(parts, "/")
+}
+
+// getJSON decodes the response of path into v and returns the next page
+// from the Link header, if any.
+func (r *Client) getJSON(path string, v any) (string, error) {
+	resp, err := r.get(path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", err
+	}
+
+	if match := nextLink.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+		return match[1], nil
+	}
+	return "", nil
+}
+
+// get issues a request, answering an authentication challenge once.
+func (r *Client) get(path string, accept ...string) (*http.Response, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	target := r.base.ResolveReference(ref)
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, target.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, mt := range accept {
+			req.Header.Add("Accept", mt)
+		}
+		r.authorize(req)
+
+		resp, err := r.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			header := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := r.login(header); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			defer resp.Body.Close()
+			return nil, responseError(resp)
+		}
+
+		return resp, nil
+	}
+}
+
+func (r *Client) authorize(req *http.Request) {
+	switch {
+	case r.token != "":
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	case r.basic && r.auth.Username != "":
+		req.SetBasicAuth(r.auth.Username, r.auth.Password)
+	}
+}
+
+// login answers a WWW-Authenticate challenge, fetching a bearer token from
+// the realm it names when needed.
+func (r *Client) login(header string) error {
+	scheme, params, _ := strings.Cut(header, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if r.auth.Username == "" {
+			return errors.New("registry requires credentials: [" + r.host + "]")
+		}
+		r.basic = true
+		return nil
+	case "bearer":
+	default:
+		return errors.New("unsupported authentication: [" + header + "]")
+	}
+
+	values := map[string]string{}
+	for _, match := range challenge.FindAllStringSubmatch(params, -1) {
+		values[match[1]] = match[2]
+	}
+
+	realm, err := url.Parse(values["realm"])
+	if err != nil {
+		return err
+	}
+	query := realm.Query()
+	for _, name := range []string{"service", "scope"} {
+		if values[name] != "" {
+			query.Set(name, values[name])
+		}
+	}
+
+	var req *http.Request
+	if r.auth.IdentityToken != "" {
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {r.auth.IdentityToken},
+			"service":       {values["service"]},
+			"scope":         {values["scope"]},
+			"client_id":     {"yadc"},
+		}
+		req, err = http.NewRequest(http.MethodPost, realm.String(), strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		realm.RawQuery = query.Encode()
+		req, err = http.NewRequest(http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return err
+		}
+		if r.auth.Username != "" {
+			req.SetBasicAuth(r.auth.Username, r.auth.Password)
+		}
+	}
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return responseError(resp)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return err
+	}
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+
+	return nil
+}
+
+func responseError(resp *http.Response) error {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+
+	if len(body.Errors) > 0 {
+		return fmt.Errorf("%s: %s (%s)", resp.Request.URL.Path, body.Errors[0].Message, body.Errors[0].Code)
+	}
+	return fmt.Errorf("%s: %s", resp.Request.URL.Path, resp.Status)
+}
+
+func isLoopback(host string) bool {
+	name, _, err := net.SplitHostPort(host)
+	if err != nil {
+		name = host
+	}
+	if name == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(name)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,324 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	regtypes "github.com/docker/docker/api/types/registry"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newServer starts a registry stub and points the docker config at an
+// empty directory so the credentials of whoever runs the tests stay out.
+func newServer(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestBearerChallenge(t *testing.T) {
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if got := r.URL.Query().Get("service"); got != "stub" {
+			t.Errorf("service %q, want stub", got)
+		}
+		if got := r.URL.Query().Get("scope"); got != "registry:catalog:*" {
+			t.Errorf("scope %q, want registry:catalog:*", got)
+		}
+		fmt.Fprint(w, `{"token":"t0ken"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.Header().Set("WWW-Authenticate",
+				`Bearer realm="`+srv.URL+`/token",service="stub",scope="registry:catalog:*"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+	srv = newServer(t, mux)
+
+	r, err := New(srv.URL, WithAuth(regtypes.AuthConfig{Username: "alice", Password: "secret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.token != "t0ken" {
+		t.Errorf("token %q, want t0ken", r.token)
+	}
+}
+
+func TestBearerAccessToken(t *testing.T) {
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("refresh_token") != "refresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"acc3ss"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer acc3ss" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="stub"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+	srv = newServer(t, mux)
+
+	if _, err := New(srv.URL, WithAuth(regtypes.AuthConfig{IdentityToken: "refresh"})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBasicChallenge(t *testing.T) {
+	srv := newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bob" || pass != "hunter2" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="stub"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+
+	if _, err := New(srv.URL, WithAuth(regtypes.AuthConfig{Username: "bob", Password: "hunter2"})); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := New(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "requires credentials") {
+		t.Errorf("without credentials got %v, want a credentials error", err)
+	}
+}
+
+func TestChallengeAnsweredOnce(t *testing.T) {
+	var requests atomic.Int32
+	srv := newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("WWW-Authenticate", `Basic realm="stub"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	_, err := New(srv.URL, WithAuth(regtypes.AuthConfig{Username: "bob", Password: "wrong"}))
+	if err == nil {
+		t.Fatal("rejected credentials gave no error")
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d requests, want the first and a single retry", n)
+	}
+}
+
+func TestUnsupportedChallenge(t *testing.T) {
+	srv := newServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Negotiate`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	if _, err := New(srv.URL); err == nil {
+		t.Error("unsupported challenge gave no error")
+	}
+}
+
+func TestPagination(t *testing.T) {
+	pages := map[string]struct {
+		repos []string
+		next  string
+	}{
+		"":        {[]string{"alpine", "busybox"}, `</v2/_catalog?last=busybox&n=100>; rel="next"`},
+		"busybox": {[]string{"debian", "nginx"}, `</v2/_catalog?last=nginx&n=100>; rel=next`},
+		"nginx":   {[]string{"redis"}, ""},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/v2/_catalog", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("n") != "100" {
+			t.Errorf("page size %q, want 100", r.URL.Query().Get("n"))
+		}
+		page, ok := pages[r.URL.Query().Get("last")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if page.next != "" {
+			w.Header().Set("Link", page.next)
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"repositories": page.repos})
+	})
+	mux.HandleFunc("/v2/alpine/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("last") == "" {
+			w.Header().Set("Link", `</v2/alpine/tags/list?last=3.19&n=100>; rel="next"`)
+			fmt.Fprint(w, `{"name":"alpine","tags":["3.18","3.19"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"name":"alpine","tags":["latest"]}`)
+	})
+	srv := newServer(t, mux)
+
+	r, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repos, err := r.Repositories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"alpine"}, {"busybox"}, {"debian"}, {"nginx"}, {"redis"}}
+	if !reflect.DeepEqual(repos.Data, want) {
+		t.Errorf("repositories %v, want %v", repos.Data, want)
+	}
+
+	tags, err := r.Tags("alpine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = [][]string{{"3.18", "alpine"}, {"3.19", "alpine"}, {"latest", "alpine"}}
+	if !reflect.DeepEqual(tags.Data, want) {
+		t.Errorf("tags %v, want %v", tags.Data, want)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/v2/missing/tags/list", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors":[{"code":"NAME_UNKNOWN","message":"repository name not known to registry"}]}`)
+	})
+	srv := newServer(t, mux)
+
+	r, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Tags("missing")
+	if err == nil || !strings.Contains(err.Error(), "NAME_UNKNOWN") {
+		t.Errorf("got %v, want the registry error code", err)
+	}
+}
+
+// writeHelper puts a docker-credential-<name> on the PATH that prints out,
+// or fails when out is empty.
+func writeHelper(t *testing.T, dir string, name string, out string) {
+	t.Helper()
+	script := "#!/bin/sh\nexit 1\n"
+	if out != "" {
+		script = "#!/bin/sh\ncat >/dev/null\necho '" + out + "'\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docker-credential-"+name), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAuth(t *testing.T) {
+	const host = "registry.example.com"
+	inline := base64.StdEncoding.EncodeToString([]byte("inline:pass"))
+
+	tests := []struct {
+		name   string
+		config string
+		want   regtypes.AuthConfig
+	}{
+		{
+			name:   "no config",
+			config: "",
+			want:   regtypes.AuthConfig{ServerAddress: host},
+		},
+		{
+			name: "credential helper first",
+			config: `{"credHelpers":{"` + host + `":"per"},"credsStore":"store",
+				"auths":{"` + host + `":{"auth":"` + inline + `"}}}`,
+			want: regtypes.AuthConfig{ServerAddress: host, Username: "per-user", Password: "per-secret"},
+		},
+		{
+			name:   "credential store next",
+			config: `{"credsStore":"store","auths":{"` + host + `":{"auth":"` + inline + `"}}}`,
+			want:   regtypes.AuthConfig{ServerAddress: host, Username: "store-user", Password: "store-secret"},
+		},
+		{
+			name:   "helper for another host",
+			config: `{"credHelpers":{"other.example.com":"per"},"credsStore":"store"}`,
+			want:   regtypes.AuthConfig{ServerAddress: host, Username: "store-user", Password: "store-secret"},
+		},
+		{
+			name:   "failing helper falls back to auths",
+			config: `{"credsStore":"broken","auths":{"` + host + `":{"auth":"` + inline + `"}}}`,
+			want:   regtypes.AuthConfig{ServerAddress: host, Username: "inline", Password: "pass", Auth: inline},
+		},
+		{
+			name:   "auths with scheme and path",
+			config: `{"auths":{"https://` + host + `/v1/":{"username":"plain","password":"text"}}}`,
+			want:   regtypes.AuthConfig{ServerAddress: host, Username: "plain", Password: "text"},
+		},
+		{
+			name:   "identity token",
+			config: `{"credsStore":"token"}`,
+			want:   regtypes.AuthConfig{ServerAddress: host, IdentityToken: "refresh"},
+		},
+		{
+			name:   "nothing for host",
+			config: `{"auths":{"other.example.com":{"auth":"` + inline + `"}}}`,
+			want:   regtypes.AuthConfig{ServerAddress: host},
+		},
+	}
+
+	bin := t.TempDir()
+	writeHelper(t, bin, "per", `{"Username":"per-user","Secret":"per-secret"}`)
+	writeHelper(t, bin, "store", `{"Username":"store-user","Secret":"store-secret"}`)
+	writeHelper(t, bin, "token", `{"Username":"<token>","Secret":"refresh"}`)
+	writeHelper(t, bin, "broken", "")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("DOCKER_CONFIG", dir)
+			if tt.config != "" {
+				if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(tt.config), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := LoadAuth(host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAuthInvalid(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"auths":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadAuth("registry.example.com"); err == nil {
+		t.Error("truncated config gave no error")
+	}
+}
@@ -0,0 +1,91 @@
+package table
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/presselam/yadc/internal/dialog"
+	"github.com/presselam/yadc/internal/logger"
+	"strings"
+)
+
+// most items listed by the bulk dialogs, the rest are counted
+const bulkListMax = 10
+
+type bulkResult struct {
+	id     string
+	result string
+	err    error
+}
+
+// bulkMsg reports the outcome of an action run over the marked rows.
+type bulkMsg struct {
+	title   string
+	results []bulkResult
+}
+
+// confirmBulk asks before running each over the marked rows, listing them.
+func (m *Model) confirmBulk(title string, ids []string, each func(string) (string, error)) {
+	logger.Trace(title, ids)
+	lines := make([]string, 0, len(ids))
+	for _, id := range ids {
+		lines = append(lines, formatID(id))
+	}
+
+	m.focus = DialogFocus
+	m.confirm = dialog.NewDialog(
+		fmt.Sprintf("%s %d items", title, len(ids)),
+		bulkList(lines),
+		"Confirm", "Dismiss",
+	)
+	m.action = func(m *Model, _ string) {
+		m.pending = runBulk(title, ids, each)
+	}
+}
+
+// runBulk goes through the items one at a time, carrying on past failures.
+func runBulk(title string, ids []string, each func(string) (string, error)) tea.Cmd {
+	return func() tea.Msg {
+		msg := bulkMsg{title: title}
+		for _, id := range ids {
+			result, err := each(id)
+			if err != nil {
+				logger.Error("table.bulk.runBulk:", id, err)
+			}
+			msg.results = append(msg.results, bulkResult{id, result, err})
+		}
+		return msg
+	}
+}
+
+func (m *Model) bulkDone(msg bulkMsg) {
+	failed := 0
+	lines := make([]string, 0, len(msg.results))
+	for _, r := range msg.results {
+		switch {
+		case r.err != nil:
+			failed++
+			lines = append(lines, fmt.Sprintf("✗ %s: %v", formatID(r.id), r.err))
+		case r.result != "":
+			lines = append(lines, fmt.Sprintf("✓ %s: %s", formatID(r.id), r.result))
+		default:
+			lines = append(lines, "✓ "+formatID(r.id))
+		}
+	}
+
+	m.table.ClearMarks()
+	m.SetContext(m.context)
+
+	title := fmt.Sprintf("%s: %d done", msg.title, len(msg.results)-failed)
+	if failed > 0 {
+		title += fmt.Sprintf(", %d failed", failed)
+	}
+	m.notify(title, bulkList(lines))
+}
+
+func bulkList(lines []string) string {
+	if len(lines) > bulkListMax {
+		more := len(lines) - bulkListMax
+		lines = append(lines[:bulkListMax:bulkListMax], fmt.Sprintf("... and %d more", more))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,123 @@
+package table
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/presselam/yadc/internal/bubble"
+	"github.com/presselam/yadc/internal/config"
+	"github.com/presselam/yadc/internal/dialog"
+	"github.com/presselam/yadc/internal/logger"
+	"slices"
+)
+
+var KeyColumns = key.NewBinding(key.WithKeys(","), key.WithHelp(",", "columns"))
+
+// contextNames are the names the column choices of each context are saved
+// under in the config.
+var contextNames = map[ContextState]string{
+	ImageContext:     "images",
+	ContainerContext: "containers",
+	VolumeContext:    "volumes",
+	InspectContext:   "inspect",
+	LogsContext:      "logs",
+	LayersContext:    "layers",
+	FilesContext:     "files",
+	WasteContext:     "waste",
+	RegistryContext:  "registry",
+	TagsContext:      "tags",
+	ManifestContext:  "manifests",
+	PackagesContext:  "packages",
+	UsageContext:     "usage",
+	GraphContext:     "graph",
+}
+
+// defaultColumns are shown when nothing else was chosen, contexts missing
+// here show all they have.
+var defaultColumns = map[ContextState][]string{
+	ContainerContext: {"ID", "Name", "Image", "State", "Ports"},
+	ImageContext:     {"ID", "Name", "Containers", "Size", "Digest", "Platform", "Created"},
+	VolumeContext:    {"Name", "Driver", "Scope", "Created", "Mountpoint"},
+	InspectContext:   {"Name", "Value"},
+}
+
+// columnsFor returns the titles of the columns shown in context, in order,
+// nil for all of them.
+func columnsFor(context ContextState) []string {
+	if chosen := config.Columns(contextNames[context]); len(chosen) > 0 {
+		return chosen
+	}
+	return defaultColumns[context]
+}
+
+// applyColumns shows the chosen columns of the current context.
+func (m *Model) applyColumns() {
+	chosen := columnsFor(m.context)
+	if chosen == nil {
+		m.table.SetOrder(nil)
+		return
+	}
+
+	columns := m.table.Columns()
+	order := []int{}
+	for _, title := range chosen {
+		idx := slices.IndexFunc(columns, func(c bubble.Column) bool { return c.Title == title })
+		if idx >= 0 {
+			order = append(order, idx)
+		}
+	}
+	if len(order) == 0 {
+		order = nil
+	}
+	m.table.SetOrder(order)
+}
+
+// pickColumns opens the column picker, shown columns first in their order
+// and the others after them.
+func (m *Model) pickColumns() {
+	logger.Trace(m.context)
+	columns := m.table.Columns()
+	if len(columns) == 0 {
+		return
+	}
+
+	items := []dialog.PickerItem{}
+	shown := m.table.Order()
+	for _, idx := range shown {
+		items = append(items, dialog.PickerItem{Label: columns[idx].Title, Checked: true})
+	}
+	for idx, col := range columns {
+		if !slices.Contains(shown, idx) {
+			items = append(items, dialog.PickerItem{Label: col.Title})
+		}
+	}
+
+	m.focus = PickerFocus
+	m.picker = dialog.NewPicker("Columns: "+contextNames[m.context], items...)
+}
+
+// pickerDone saves the columns chosen in the picker and shows them.
+func (m *Model) pickerDone() {
+	m.focus = TableFocus
+
+	var chosen []string
+	if !m.picker.Reset() {
+		chosen = m.picker.Checked()
+		if len(chosen) == 0 {
+			m.notify("Columns", "At least one column has to be shown")
+			return
+		}
+	}
+
+	// the choice holds for the session even when it cannot be saved
+	err := config.SetColumns(contextNames[m.context], chosen)
+	if err != nil {
+		logger.Error("table.columns.pickerDone:", err)
+		m.notify("Columns Not Saved", err.Error())
+	}
+
+	// container sizes are only fetched when shown
+	if m.context == ContainerContext {
+		m.PopulateContainers()
+		return
+	}
+	m.applyColumns()
+}
@@ -1,33 +1,44 @@
 package table
 
 import (
+	"fmt"
 	"github.com/charmbracelet/bubbles/key"
-	"github.com/presselam/yadc/internal/bubble"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/presselam/yadc/internal/dialog"
 	"github.com/presselam/yadc/internal/docker"
 	"github.com/presselam/yadc/internal/logger"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 )
 
+// sizeInterval is how long the container sizes are kept, working them out
+// has the engine walk the filesystem of every container.
+const sizeInterval = 30 * time.Second
+
 func (m *Model) PopulateContainers() error {
-	results, err := docker.Containers()
+	withSize := slices.Contains(columnsFor(ContainerContext), "Size") && time.Since(m.sizedAt) >= sizeInterval
+	results, err := docker.Containers(withSize)
 	if err != nil {
 		return err
 	}
 
-	total := 0
-	columns := []bubble.Column{}
-	for i, col := range results.Columns {
-		columns = append(columns, bubble.Column{Title: col, Width: results.Width[i]})
-		total += results.Width[i]
+	// in between the sizes last worked out stand in
+	if withSize {
+		m.sizes = make(map[string]string)
+		m.sizedAt = time.Now()
 	}
-
-	rows := []bubble.Row{}
-	for _, r := range results.Data {
-		rows = append(rows, r)
+	size := slices.Index(results.Columns, "Size")
+	for _, row := range results.Data {
+		if withSize {
+			m.sizes[row[0]] = row[size]
+		} else {
+			row[size] = m.sizes[row[0]]
+		}
 	}
 
-	m.table.SetData(columns, rows)
-	m.sortRows()
+	m.setResults(results)
 	return nil
 }
 
@@ -44,12 +55,21 @@ func (m *Model) containerActions() []KeyMapping {
 				key.WithKeys("ctrl+r", "ctrl+s"),
 				key.WithHelp("ctrl+r", "restart"),
 			),
+			each: func(id string) (string, error) { return "", docker.ContainerRestart(id) },
 		},
 		{cmd: (*Model).stopContainer,
 			key: key.NewBinding(
 				key.WithKeys("ctrl+k"),
-				key.WithHelp("ctrl+s", "start"),
+				key.WithHelp("ctrl+k", "stop"),
+			),
+			each: func(id string) (string, error) { return "", docker.ContainerStop(id) },
+		},
+		{cmd: (*Model).removeContainer,
+			key: key.NewBinding(
+				key.WithKeys("ctrl+d"),
+				key.WithHelp("ctrl+d", "remove"),
 			),
+			each: func(id string) (string, error) { return "", docker.ContainerRemove(id) },
 		},
 		{cmd: (*Model).pruneContainer,
 			key: key.NewBinding(
@@ -57,6 +77,36 @@ func (m *Model) containerActions() []KeyMapping {
 				key.WithHelp("ctrl+p", "prune"),
 			),
 		},
+		{cmd: (*Model).updateContainer,
+			key: key.NewBinding(
+				key.WithKeys("U"),
+				key.WithHelp("U", "update"),
+			),
+		},
+		{cmd: (*Model).commitContainer,
+			key: key.NewBinding(
+				key.WithKeys("c"),
+				key.WithHelp("c", "commit"),
+			),
+		},
+		{cmd: (*Model).cloneContainer,
+			key: key.NewBinding(
+				key.WithKeys("C"),
+				key.WithHelp("C", "clone"),
+			),
+		},
+		{cmd: (*Model).recreateContainer,
+			key: key.NewBinding(
+				key.WithKeys("R"),
+				key.WithHelp("R", "recreate"),
+			),
+		},
+		{cmd: (*Model).attachContainer,
+			key: key.NewBinding(
+				key.WithKeys("a"),
+				key.WithHelp("a", "attach"),
+			),
+		},
 		{cmd: (*Model).logContainer,
 			key: key.NewBinding(
 				key.WithKeys("l"),
@@ -80,6 +130,21 @@ func (m *Model) stopContainer(id string) {
 	m.PopulateContainers()
 }
 
+func (m *Model) removeContainer(id string) {
+	logger.Trace(id)
+	if m.focus == TableFocus {
+		m.focus = DialogFocus
+		m.confirm = dialog.NewDialog(
+			"Remove",
+			"This will remove container "+id,
+			"Confirm", "Dismiss",
+		)
+	} else {
+		go docker.ContainerRemove(id)
+		m.PopulateContainers()
+	}
+}
+
 func (m *Model) pruneContainer(id string) {
 	logger.Trace(id)
 	if m.focus == TableFocus {
@@ -95,6 +160,205 @@ func (m *Model) pruneContainer(id string) {
 	}
 }
 
+func (m *Model) updateContainer(id string) {
+	logger.Trace(id)
+	if m.focus == TableFocus {
+		spec, err := docker.ContainerUpdateSpec(id)
+		if err != nil {
+			logger.Error("table.container.updateContainer:", err)
+			return
+		}
+
+		m.selected = id
+		m.focus = FormFocus
+		m.form = dialog.NewForm("Update Container",
+			dialog.NewField("Name", spec.Name, "container name"),
+			dialog.NewField("CPU Shares", spec.CPUShares, "1024"),
+			dialog.NewField("CPU Quota", spec.CPUQuota, "50000"),
+			dialog.NewField("Memory", spec.Memory, "512m"),
+			dialog.NewField("Memory+Swap", spec.MemorySwap, "1g | -1"),
+			dialog.NewField("PIDs Limit", spec.PidsLimit, "100 | -1"),
+			dialog.NewField("Restart", spec.Restart, "no | always | unless-stopped | on-failure:3"),
+		)
+		return
+	}
+
+	err := docker.ContainerUpdate(id, docker.UpdateSpec{
+		Name:       m.form.Value("Name"),
+		CPUShares:  m.form.Value("CPU Shares"),
+		CPUQuota:   m.form.Value("CPU Quota"),
+		Memory:     m.form.Value("Memory"),
+		MemorySwap: m.form.Value("Memory+Swap"),
+		PidsLimit:  m.form.Value("PIDs Limit"),
+		Restart:    m.form.Value("Restart"),
+	})
+	if err != nil {
+		m.form.SetError(err)
+		return
+	}
+
+	m.focus = TableFocus
+	m.inspectContainer(id)
+}
+
+func (m *Model) commitContainer(id string) {
+	logger.Trace(id)
+	if m.focus == TableFocus {
+		m.selected = id
+		m.focus = FormFocus
+		m.form = dialog.NewForm("Commit Container",
+			dialog.NewField("Repository:Tag", "", "repository:tag"),
+			dialog.NewField("Author", "", "Name <email>"),
+			dialog.NewField("Message", "", "commit message"),
+			dialog.NewField("Pause", "yes", "yes | no"),
+			dialog.NewField("Changes", "", `"ENV DEBUG=1" "EXPOSE 8080" 'CMD ["app"]'`),
+		)
+		return
+	}
+
+	image, err := docker.ContainerCommit(id, docker.CommitSpec{
+		Reference: m.form.Value("Repository:Tag"),
+		Author:    m.form.Value("Author"),
+		Message:   m.form.Value("Message"),
+		Pause:     m.form.Value("Pause"),
+		Changes:   m.form.Value("Changes"),
+	})
+	if err != nil {
+		m.form.SetError(err)
+		return
+	}
+	logger.Info("Committed: ", id, " => ", image)
+
+	m.focus = TableFocus
+	m.SetContext(ImageContext)
+	m.selectRow(docker.ShortID(image))
+}
+
+func (m *Model) attachContainer(id string) {
+	logger.Trace(id)
+	m.pending = tea.Exec(docker.ContainerAttach(id), func(err error) tea.Msg {
+		return execDoneMsg{err}
+	})
+}
+
+func (m *Model) logActions() []KeyMapping {
+	retval := []KeyMapping{
+		{cmd: (*Model).exportLogs,
+			key: key.NewBinding(
+				key.WithKeys("e"),
+				key.WithHelp("e", "export"),
+			),
+		},
+	}
+
+	return retval
+}
+
+// exportMsg carries the progress of a transfer running in the background,
+// along with the channel the next update arrives on.
+type exportMsg struct {
+	updates chan exportMsg
+	title   string
+	path    string
+	written int64
+	done    bool
+	result  string
+	err     error
+}
+
+func waitExport(updates chan exportMsg) tea.Cmd {
+	return func() tea.Msg {
+		return <-updates
+	}
+}
+
+// transfer runs a long copy to or from path in the background, keeping a
+// dialog up to date with the bytes moved so far. run returns the message
+// shown once it is done.
+func (m *Model) transfer(title string, path string, run func(progress func(int64)) (string, error)) {
+	updates := make(chan exportMsg, 1)
+	go func() {
+		var written int64
+		result, err := run(func(n int64) {
+			written = n
+			// progress is best effort, never hold up the transfer for it
+			select {
+			case updates <- exportMsg{updates: updates, title: title, path: path, written: n}:
+			default:
+			}
+		})
+		updates <- exportMsg{updates: updates, title: title, path: path, written: written, done: true, result: result, err: err}
+	}()
+
+	m.notify(title, path)
+	m.pending = waitExport(updates)
+}
+
+func (m *Model) exportLogs(string) {
+	logger.Trace(m.selected)
+	if m.focus == TableFocus {
+		path := fmt.Sprintf("%s-%s.log", docker.ShortID(m.selected), time.Now().Format("20060102-150405"))
+		m.focus = FormFocus
+		m.form = dialog.NewForm("Export Logs",
+			dialog.NewField("Since", "", "2h | 2024-01-02T15:04:05"),
+			dialog.NewField("Until", "", "now"),
+			dialog.NewField("Streams", "stdout,stderr", "stdout,stderr"),
+			dialog.NewField("Timestamps", "no", "yes | no"),
+			dialog.NewField("Format", docker.LogFormatPlain, "plain | gzip"),
+			dialog.NewField("Path", path, "file"),
+		)
+		return
+	}
+
+	spec := docker.LogExportSpec{
+		Since:      m.form.Value("Since"),
+		Until:      m.form.Value("Until"),
+		Streams:    m.form.Value("Streams"),
+		Timestamps: m.form.Value("Timestamps"),
+		Format:     m.form.Value("Format"),
+		Path:       m.form.Value("Path"),
+	}
+	if spec.Format == docker.LogFormatGzip && !strings.HasSuffix(spec.Path, ".gz") {
+		spec.Path += ".gz"
+	}
+	if err := spec.Validate(); err != nil {
+		m.form.SetError(err)
+		return
+	}
+
+	id := m.selected
+	m.transfer("Export Logs", spec.Path, func(progress func(int64)) (string, error) {
+		written, err := docker.ContainerLogExport(id, spec, progress)
+		if err != nil {
+			return "", err
+		}
+
+		path, err := filepath.Abs(spec.Path)
+		if err != nil {
+			path = spec.Path
+		}
+		return fmt.Sprintf("%d bytes written to\n%s", written, path), nil
+	})
+}
+
+func (m *Model) exportProgress(msg exportMsg) tea.Cmd {
+	if !msg.done {
+		if m.focus == DialogFocus && m.action == nil {
+			m.confirm.SetMessage(fmt.Sprintf("%s\n%d bytes transferred", msg.path, msg.written))
+		}
+		return waitExport(msg.updates)
+	}
+
+	if msg.err != nil {
+		logger.Error("table.container.exportProgress:", msg.err)
+		m.notify(msg.title+" Failed", msg.err.Error())
+		return nil
+	}
+
+	m.notify(msg.title+" Complete", msg.result)
+	return nil
+}
+
 func (m *Model) logContainer(id string) {
 	logger.Trace(id)
 	m.selected = id
@@ -108,20 +372,8 @@ func (m *Model) fetchLogs() error {
 		return err
 	}
 
-	total := 0
-	columns := []bubble.Column{}
-	for i, col := range results.Columns {
-		columns = append(columns, bubble.Column{Title: col, Width: results.Width[i]})
-		total += results.Width[i]
-	}
-
-	rows := []bubble.Row{}
-	for _, r := range results.Data {
-		rows = append(rows, r)
-	}
-
-	m.table.SetData(columns, rows)
-	m.table.SetCursor(len(rows))
+	m.setResults(results)
+	m.table.SetCursor(len(results.Data))
 
 	return nil
 }
@@ -0,0 +1,197 @@
+package table
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/presselam/yadc/internal/bubble"
+	"github.com/presselam/yadc/internal/docker"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// marks a line whose nested lines are folded away
+const foldMarker = "…"
+
+var (
+	syntaxKey         = lipgloss.NewStyle().Foreground(lipgloss.Color("69"))
+	syntaxString      = lipgloss.NewStyle().Foreground(lipgloss.Color("70"))
+	syntaxNumber      = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	syntaxLiteral     = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	syntaxPunctuation = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+)
+
+// nestLevels works out how deep each line is nested from its indentation,
+// blank lines going with the line before them.
+func nestLevels(lines []string) []int {
+	levels := make([]int, len(lines))
+	var indents []int
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			levels[i] = len(indents)
+			continue
+		}
+		indent := len(line) - len(trimmed)
+		for len(indents) > 0 && indents[len(indents)-1] >= indent {
+			indents = indents[:len(indents)-1]
+		}
+		levels[i] = len(indents)
+		indents = append(indents, indent)
+	}
+	return levels
+}
+
+func maxLevel(lines []string) int {
+	retval := 0
+	for _, level := range nestLevels(lines) {
+		retval = max(retval, level)
+	}
+	return retval
+}
+
+// foldDocument lists the lines of the document nested less than fold
+// levels deep, all of them for 0, numbered as in the whole document.
+func foldDocument(title string, lines []string, fold int) docker.Results {
+	retval := docker.Results{
+		Columns: []string{"Line", title},
+		Data:    [][]string{},
+		Width:   []int{0, 0},
+	}
+
+	levels := nestLevels(lines)
+	for i, line := range lines {
+		if fold > 0 && levels[i] >= fold {
+			continue
+		}
+		if fold > 0 && i+1 < len(lines) && levels[i+1] >= fold {
+			line += " " + foldMarker
+		}
+
+		row := []string{strconv.Itoa(i + 1), line}
+		retval.Data = append(retval.Data, row)
+		for i, val := range row {
+			if len(val) > retval.Width[i] {
+				retval.Width[i] = len(val)
+			}
+		}
+	}
+
+	return retval
+}
+
+// highlightJSON picks out the keys, strings, numbers and literals of a
+// line of indented JSON.
+func highlightJSON(line string) []bubble.Span {
+	var spans []bubble.Span
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		end := i + 1
+		switch r := runes[i]; {
+		case r == '"':
+			for end < len(runes) && runes[end] != '"' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(runes))
+			style := syntaxString
+			if strings.HasPrefix(strings.TrimSpace(string(runes[end:])), ":") {
+				style = syntaxKey
+			}
+			spans = append(spans, bubble.Span{Start: i, End: end, Style: style})
+		case r == '-' || unicode.IsDigit(r):
+			for end < len(runes) && strings.ContainsRune("0123456789.eE+-", runes[end]) {
+				end++
+			}
+			spans = append(spans, bubble.Span{Start: i, End: end, Style: syntaxNumber})
+		case unicode.IsLetter(r):
+			for end < len(runes) && unicode.IsLetter(runes[end]) {
+				end++
+			}
+			spans = append(spans, bubble.Span{Start: i, End: end, Style: syntaxLiteral})
+		case strings.ContainsRune("{}[],:"+foldMarker, r):
+			spans = append(spans, bubble.Span{Start: i, End: end, Style: syntaxPunctuation})
+		}
+		i = end
+	}
+	return spans
+}
+
+// highlightYAML picks out the list markers, key and value of a line of
+// YAML as yaml.v3 writes it.
+func highlightYAML(line string) []bubble.Span {
+	var spans []bubble.Span
+	runes := []rune(line)
+
+	last := len(runes)
+	if strings.HasSuffix(line, " "+foldMarker) {
+		last--
+		spans = append(spans, bubble.Span{Start: last, End: last + 1, Style: syntaxPunctuation})
+	}
+
+	i := 0
+	skipSpaces := func() {
+		for i < last && runes[i] == ' ' {
+			i++
+		}
+	}
+	skipSpaces()
+	for i < last && runes[i] == '-' && (i+1 == last || runes[i+1] == ' ') {
+		spans = append(spans, bubble.Span{Start: i, End: i + 1, Style: syntaxPunctuation})
+		i++
+		skipSpaces()
+	}
+
+	if colon := yamlKeyEnd(runes[:last], i); colon > i {
+		spans = append(spans,
+			bubble.Span{Start: i, End: colon, Style: syntaxKey},
+			bubble.Span{Start: colon, End: colon + 1, Style: syntaxPunctuation},
+		)
+		i = colon + 1
+		skipSpaces()
+	}
+
+	value := strings.TrimRight(string(runes[i:last]), " ")
+	if value == "" {
+		return spans
+	}
+	style := syntaxString
+	switch {
+	case value == "true" || value == "false" || value == "null" || value == "~":
+		style = syntaxLiteral
+	case value == "{}" || value == "[]" || strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+		style = syntaxPunctuation
+	default:
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			style = syntaxNumber
+		}
+	}
+	return append(spans, bubble.Span{Start: i, End: i + len([]rune(value)), Style: style})
+}
+
+// yamlKeyEnd returns where the colon ending the key that starts at start
+// is, -1 when the line holds no key there.
+func yamlKeyEnd(runes []rune, start int) int {
+	i := start
+	if i < len(runes) && (runes[i] == '"' || runes[i] == '\'') {
+		quote := runes[i]
+		for i++; i < len(runes) && runes[i] != quote; i++ {
+			if runes[i] == '\\' && quote == '"' {
+				i++
+			}
+		}
+		i++
+		if i < len(runes) && runes[i] == ':' && (i+1 == len(runes) || runes[i+1] == ' ') {
+			return i
+		}
+		return -1
+	}
+
+	for ; i < len(runes); i++ {
+		if runes[i] == ':' && (i+1 == len(runes) || runes[i+1] == ' ') {
+			return i
+		}
+	}
+	return -1
+}
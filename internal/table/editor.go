@@ -0,0 +1,44 @@
+package table
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editorCommand builds the command that opens path in the user's editor,
+// honouring $VISUAL and $EDITOR the way git does. A blank variable counts
+// as unset.
+func editorCommand(path string) *exec.Cmd {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	args := strings.Fields(editor)
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+// pagerCommand builds the command that pages through its input, $PAGER
+// or else less when it is unset or blank.
+func pagerCommand() *exec.Cmd {
+	pager := strings.TrimSpace(os.Getenv("PAGER"))
+	if pager == "" {
+		pager = "less"
+	}
+
+	args := strings.Fields(pager)
+	return exec.Command(args[0], args[1:]...)
+}
+
+// editFile hands the terminal to the editor on path and reports back with
+// the message built by done.
+func editFile(path string, done func(error) tea.Msg) tea.Cmd {
+	return tea.ExecProcess(editorCommand(path), func(err error) tea.Msg {
+		return done(err)
+	})
+}
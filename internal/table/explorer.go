@@ -0,0 +1,123 @@
+package table
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+	"github.com/presselam/yadc/internal/bubble"
+	"github.com/presselam/yadc/internal/docker"
+	"github.com/presselam/yadc/internal/logger"
+	"strconv"
+)
+
+func (m *Model) layerActions() []KeyMapping {
+	retval := []KeyMapping{
+		{cmd: (*Model).openLayer,
+			key: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "files"),
+			),
+		},
+		{cmd: (*Model).wastedFiles,
+			key: key.NewBinding(
+				key.WithKeys("w"),
+				key.WithHelp("w", "wasted"),
+			),
+		},
+	}
+
+	return retval
+}
+
+// layersMsg carries the layers of an image, read in the background.
+type layersMsg struct {
+	id       string
+	analysis docker.ImageAnalysis
+	err      error
+}
+
+// exploreImage reads the layers off the main loop, saving a large image
+// taking a while.
+func (m *Model) exploreImage(id string) {
+	logger.Trace(id)
+	m.loading("Explore", "Reading the layers of "+id, func() tea.Msg {
+		analysis, err := docker.ImageLayers(id)
+		return layersMsg{id, analysis, err}
+	})
+}
+
+func (m *Model) exploreDone(msg layersMsg) {
+	m.loaded()
+	if msg.err != nil {
+		logger.Error("table.explorer.exploreDone:", msg.err)
+		m.notify("Explore Failed", msg.err.Error())
+		return
+	}
+
+	m.selected = msg.id
+	m.analysis = msg.analysis
+	m.SetContext(LayersContext)
+}
+
+func (m *Model) openLayer(id string) {
+	logger.Trace(id)
+	if id == docker.LayerTotal {
+		m.wastedFiles(id)
+		return
+	}
+
+	index, err := strconv.Atoi(id)
+	if err != nil {
+		return
+	}
+	m.layer = index
+	m.SetContext(FilesContext)
+}
+
+func (m *Model) wastedFiles(id string) {
+	logger.Trace(id)
+	m.SetContext(WasteContext)
+}
+
+func (m *Model) populateLayers() error {
+	m.setResults(m.analysis.LayerResults())
+	return nil
+}
+
+func (m *Model) populateLayerFiles() error {
+	m.setResults(m.analysis.FileResults(m.layer))
+	m.table.SetCursor(0)
+	return nil
+}
+
+func (m *Model) populateWaste() error {
+	m.setResults(m.analysis.WasteResults())
+	m.table.SetCursor(0)
+	return nil
+}
+
+func (m *Model) setResults(results docker.Results) {
+	columns := []bubble.Column{}
+	for i, col := range results.Columns {
+		column := columnSpecs[col]
+		column.Title = col
+		column.Format = valueFormatter(col, column.Type)
+
+		// the width goes by what is shown on screen, not by the bytes of
+		// the raw values
+		for _, r := range results.Data {
+			if i < len(r) {
+				column.Width = max(column.Width, runewidth.StringWidth(column.Display(r[i])))
+			}
+		}
+		columns = append(columns, column)
+	}
+
+	rows := []bubble.Row{}
+	for _, r := range results.Data {
+		rows = append(rows, r)
+	}
+
+	m.table.SetData(columns, rows)
+	m.applyColumns()
+}
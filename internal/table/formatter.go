@@ -3,9 +3,82 @@ package table
 import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
 	"github.com/presselam/yadc/internal/bubble"
+	"github.com/presselam/yadc/internal/docker"
+	"strconv"
+	"strings"
+	"time"
 )
 
+var (
+	location      = time.Local
+	relativeTimes = true
+	longIDs       = false
+)
+
+// SetTimezone sets the zone absolute times are shown in, an IANA name such
+// as Europe/Paris, Local or UTC.
+func SetTimezone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+	location = loc
+	return nil
+}
+
+// SetRelativeTimes shows times as "3 days ago" rather than as a date.
+func SetRelativeTimes(relative bool) {
+	relativeTimes = relative
+}
+
+// SetLongIDs shows IDs in full rather than trimmed.
+func SetLongIDs(long bool) {
+	longIDs = long
+}
+
+// valueFormatter picks how the raw values of a column are shown, nil
+// showing them as they are.
+func valueFormatter(title string, kind bubble.ColumnType) func(string) string {
+	switch {
+	case title == "ID":
+		return formatID
+	case kind == bubble.ColumnBytes:
+		return formatBytes
+	case kind == bubble.ColumnTime:
+		return formatTime
+	}
+	return nil
+}
+
+// formatID trims the content addressed IDs, leaving names alone.
+func formatID(value string) string {
+	if longIDs || len(value) <= 12 || strings.Trim(value, "0123456789abcdef") != "" {
+		return value
+	}
+	return docker.ShortID(value)
+}
+
+func formatBytes(value string) string {
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return value
+	}
+	return units.HumanSize(float64(size))
+}
+
+func formatTime(value string) string {
+	t, ok := bubble.ParseTime(value)
+	if !ok || t.IsZero() {
+		return value
+	}
+	if relativeTimes {
+		return units.HumanDuration(time.Since(t)) + " ago"
+	}
+	return t.In(location).Format(time.DateTime)
+}
+
 func ContainerFormatter(row bubble.Row) lipgloss.Style {
 	style := lipgloss.NewStyle()
 
@@ -44,3 +117,20 @@ func ImageFormatter(row bubble.Row) lipgloss.Style {
 
 	return style
 }
+
+func LayerFileFormatter(row bubble.Row) lipgloss.Style {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("225"))
+
+	if len(row) > 0 {
+		switch docker.FileStatus(row[0]) {
+		case docker.FileAdded:
+			style = style.Foreground(lipgloss.Color("82"))
+		case docker.FileModified:
+			style = style.Foreground(lipgloss.Color("214"))
+		case docker.FileRemoved:
+			style = style.Foreground(lipgloss.Color("196"))
+		}
+	}
+
+	return style
+}
@@ -0,0 +1,105 @@
+package table
+
+import (
+	"errors"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/presselam/yadc/internal/docker"
+	"github.com/presselam/yadc/internal/logger"
+)
+
+const graphDepth = 2
+
+func (m *Model) graphActions() []KeyMapping {
+	retval := []KeyMapping{
+		{cmd: (*Model).openNode,
+			key: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "open"),
+			),
+		},
+		{cmd: (*Model).centerNode,
+			key: key.NewBinding(
+				key.WithKeys("c"),
+				key.WithHelp("c", "center"),
+			),
+		},
+	}
+
+	return retval
+}
+
+// ShowGraph switches to the relationship graph of the selected container
+// or image.
+func (m *Model) ShowGraph() error {
+	row := m.table.SelectedRow()
+	switch {
+	case m.context == GraphContext:
+	case m.context == ContainerContext && len(row) > 0:
+		m.center = docker.NodeKey(docker.NodeContainer, docker.ShortID(row[0]))
+	case m.context == ImageContext && len(row) > 0:
+		m.center = docker.NodeKey(docker.NodeImage, docker.ShortID(row[0]))
+	default:
+		return errors.New("select a container or an image first")
+	}
+
+	return m.SetContext(GraphContext)
+}
+
+func (m *Model) populateGraph() error {
+	graph, err := docker.ResourceGraph()
+	if err != nil {
+		return err
+	}
+
+	m.graph = graph.Tree(m.center, graphDepth)
+	m.setResults(docker.GraphResults(m.graph))
+	m.table.SetCursor(0)
+	return nil
+}
+
+// selectedNode is the node under the cursor, the rows of the graph being
+// a tree they are looked up by position rather than by their first column.
+func (m *Model) selectedNode() (docker.GraphNode, bool) {
+	cursor := m.table.SelectedIndex()
+	if cursor < 0 || cursor >= len(m.graph) {
+		return docker.GraphNode{}, false
+	}
+	return m.graph[cursor].Node, true
+}
+
+// openNode jumps to the mode listing the node with it selected, nodes
+// without a mode of their own become the center of the graph instead.
+func (m *Model) openNode(string) {
+	node, ok := m.selectedNode()
+	if !ok {
+		return
+	}
+	logger.Trace(node.Key())
+
+	switch node.Kind {
+	case docker.NodeContainer:
+		m.SetContext(ContainerContext)
+	case docker.NodeImage:
+		m.SetContext(ImageContext)
+	case docker.NodeVolume:
+		m.SetContext(VolumeContext)
+	default:
+		m.centerNode("")
+		return
+	}
+	m.selectRow(node.ID)
+}
+
+func (m *Model) centerNode(string) {
+	node, ok := m.selectedNode()
+	if !ok {
+		return
+	}
+	logger.Trace(node.Key())
+
+	m.center = node.Key()
+	err := m.SetContext(GraphContext)
+	if err != nil {
+		logger.Error("table.graph.centerNode:", err)
+	}
+}
@@ -2,7 +2,6 @@ package table
 
 import (
 	"github.com/charmbracelet/bubbles/key"
-	"github.com/presselam/yadc/internal/bubble"
 	"github.com/presselam/yadc/internal/dialog"
 	"github.com/presselam/yadc/internal/docker"
 	"github.com/presselam/yadc/internal/logger"
@@ -23,11 +22,42 @@ func (m *Model) imageActions() []KeyMapping {
 				key.WithHelp("ctrl+r", "restart"),
 			),
 		},
+		{cmd: (*Model).manifestImage,
+			key: key.NewBinding(
+				key.WithKeys("m"),
+				key.WithHelp("m", "manifests"),
+			),
+		},
+		{cmd: (*Model).exploreImage,
+			key: key.NewBinding(
+				key.WithKeys("e"),
+				key.WithHelp("e", "explore layers"),
+			),
+		},
+		{cmd: (*Model).inventoryImage,
+			key: key.NewBinding(
+				key.WithKeys("s"),
+				key.WithHelp("s", "sbom"),
+			),
+		},
+		{cmd: (*Model).usageImages,
+			key: key.NewBinding(
+				key.WithKeys("D"),
+				key.WithHelp("D", "disk usage"),
+			),
+		},
+		{cmd: (*Model).runImage,
+			key: key.NewBinding(
+				key.WithKeys("r"),
+				key.WithHelp("r", "run"),
+			),
+		},
 		{cmd: (*Model).removeImage,
 			key: key.NewBinding(
 				key.WithKeys("ctrl+d"),
 				key.WithHelp("ctrl+d", "remove"),
 			),
+			each: docker.ImageDelete,
 		},
 		{cmd: (*Model).pruneImages,
 			key: key.NewBinding(
@@ -38,8 +68,9 @@ func (m *Model) imageActions() []KeyMapping {
 		{cmd: (*Model).saveImage,
 			key: key.NewBinding(
 				key.WithKeys("ctrl+s"),
-				key.WithHelp("l", "logs"),
+				key.WithHelp("ctrl+s", "save"),
 			),
+			each: docker.ImageSave,
 		},
 	}
 
@@ -53,18 +84,7 @@ func (m *Model) PopulateImages() error {
 		return err
 	}
 
-	columns := []bubble.Column{}
-	for i, col := range results.Columns {
-		columns = append(columns, bubble.Column{Title: col, Width: results.Width[i]})
-	}
-
-	rows := []bubble.Row{}
-	for _, r := range results.Data {
-		rows = append(rows, r)
-	}
-
-	m.table.SetData(columns, rows)
-	m.sortRows()
+	m.setResults(results)
 	return nil
 }
 
@@ -73,19 +93,72 @@ func (m *Model) historyImage(id string) {
 	m.SetContext(InspectContext)
 	results, _ := docker.ImageHistory(id)
 
-	total := 0
-	columns := []bubble.Column{}
-	for i, col := range results.Columns {
-		columns = append(columns, bubble.Column{Title: col, Width: results.Width[i]})
-		total += results.Width[i]
+	m.setResults(results)
+}
+
+func (m *Model) manifestImage(id string) {
+	logger.Trace(id)
+	results, err := docker.ImageManifests(id)
+	if err != nil {
+		logger.Error("table.image.manifestImage:", err)
+		m.notify("Manifests Failed", err.Error())
+		return
 	}
 
-	rows := []bubble.Row{}
-	for _, r := range results.Data {
-		rows = append(rows, r)
+	m.SetContext(InspectContext)
+	m.setResults(results)
+}
+
+func (m *Model) runImage(id string) {
+	logger.Trace(id)
+	if m.focus == TableFocus {
+		name := id
+		if row := m.table.SelectedRow(); len(row) > 1 && row[1] != "<none>" {
+			name = row[1]
+		}
+
+		m.selected = id
+		m.focus = FormFocus
+		m.form = dialog.NewForm("Run Container",
+			dialog.NewField("Image", name, "image reference"),
+			dialog.NewField("Name", "", "generated"),
+			dialog.NewField("Command", "", "image default"),
+			dialog.NewField("Env", "", "KEY=value ..."),
+			dialog.NewField("Ports", "", "8080:80/tcp ..."),
+			dialog.NewField("Volumes", "", "volume:/data /host:/path:ro ..."),
+			dialog.NewField("Network", "", "default"),
+			dialog.NewField("Restart", "", "no | always | unless-stopped | on-failure:3"),
+			dialog.NewField("CPUs", "", "1.5"),
+			dialog.NewField("Memory", "", "512m"),
+		)
+		m.form.SetPreview(func(f dialog.Form) string {
+			return runSpec(f).CommandLine()
+		})
+		return
+	}
+
+	id, err := docker.ContainerRun(runSpec(m.form))
+	if err != nil {
+		m.form.SetError(err)
+		return
 	}
+	logger.Info("Started container: ", id)
+	m.focus = TableFocus
+}
 
-	m.table.SetData(columns, rows)
+func runSpec(f dialog.Form) docker.RunSpec {
+	return docker.RunSpec{
+		Image:   f.Value("Image"),
+		Name:    f.Value("Name"),
+		Command: f.Value("Command"),
+		Env:     f.Value("Env"),
+		Ports:   f.Value("Ports"),
+		Volumes: f.Value("Volumes"),
+		Network: f.Value("Network"),
+		Restart: f.Value("Restart"),
+		CPUs:    f.Value("CPUs"),
+		Memory:  f.Value("Memory"),
+	}
 }
 
 func (m *Model) removeImage(id string) {
@@ -1,47 +1,317 @@
 package table
 
 import (
-	"github.com/presselam/yadc/internal/bubble"
+	"bytes"
+	"errors"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/presselam/yadc/internal/dialog"
 	"github.com/presselam/yadc/internal/docker"
 	"github.com/presselam/yadc/internal/logger"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
-func (m *Model) inspectContainer(id string) {
-	logger.Debug("table.inspector.container.inspect.", id)
-	m.SetContext(InspectContext)
-	results, _ := docker.ContainerInspect(id)
+type inspectView uint
+
+const (
+	treeView inspectView = iota
+	jsonView
+	yamlView
+)
+
+// format is the document format of the view, the tree going out as JSON.
+func (v inspectView) format() string {
+	if v == yamlView {
+		return docker.DocumentYAML
+	}
+	return docker.DocumentJSON
+}
 
-	total := 0
-	columns := []bubble.Column{}
-	for i, col := range results.Columns {
-		columns = append(columns, bubble.Column{Title: col, Width: results.Width[i]})
-		total += results.Width[i]
+// inspectActions work the inspection, history and manifests sharing the
+// context have none.
+func (m *Model) inspectActions() []KeyMapping {
+	if m.inspection == nil {
+		return nil
 	}
 
-	rows := []bubble.Row{}
-	for _, r := range results.Data {
-		rows = append(rows, r)
+	retval := []KeyMapping{
+		{cmd: (*Model).nextView,
+			key: key.NewBinding(
+				key.WithKeys("v"),
+				key.WithHelp("v", "tree/json/yaml"),
+			),
+		},
+		{cmd: (*Model).writeDocument,
+			key: key.NewBinding(
+				key.WithKeys("w"),
+				key.WithHelp("w", "write"),
+			),
+		},
+		{cmd: (*Model).pageDocument,
+			key: key.NewBinding(
+				key.WithKeys("|"),
+				key.WithHelp("|", "pager"),
+			),
+		},
+		{cmd: (*Model).editDocument,
+			key: key.NewBinding(
+				key.WithKeys("ctrl+e"),
+				key.WithHelp("ctrl+e", "editor"),
+			),
+		},
+	}
+
+	if m.view != treeView {
+		return append(retval,
+			KeyMapping{cmd: (*Model).unfoldLevel,
+				key: key.NewBinding(
+					key.WithKeys("+", "="),
+					key.WithHelp("+", "unfold"),
+				),
+			},
+			KeyMapping{cmd: (*Model).foldLevel,
+				key: key.NewBinding(
+					key.WithKeys("-"),
+					key.WithHelp("-", "fold"),
+				),
+			},
+		)
+	}
+
+	return append(retval,
+		KeyMapping{cmd: (*Model).toggleNode,
+			key: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "open/close"),
+			),
+		},
+		KeyMapping{cmd: (*Model).expandAll,
+			key: key.NewBinding(
+				key.WithKeys("e"),
+				key.WithHelp("e", "expand all"),
+			),
+		},
+		KeyMapping{cmd: (*Model).collapseAll,
+			key: key.NewBinding(
+				key.WithKeys("c"),
+				key.WithHelp("c", "collapse all"),
+			),
+		},
+		KeyMapping{cmd: (*Model).showPath,
+			key: key.NewBinding(
+				key.WithKeys("p"),
+				key.WithHelp("p", "path"),
+			),
+		},
+	)
+}
+
+func (m *Model) inspectContainer(id string) {
+	logger.Debug("table.inspector.container.inspect.", id)
+	inspection, err := docker.ContainerInspect(id)
+	if err != nil {
+		logger.Error("table.inspector.inspectContainer:", err)
+		m.notify("Inspect Failed", err.Error())
+		return
 	}
 
-	m.table.SetData(columns, rows)
+	m.SetContext(InspectContext)
+	m.selected = id
+	m.showInspection(inspection)
 }
 
 func (m *Model) inspectImage(id string) {
 	logger.Debug("tabel.inspector.image.inspect.", id)
+	inspection, err := docker.ImageInspect(id)
+	if err != nil {
+		logger.Error("table.inspector.inspectImage:", err)
+		m.notify("Inspect Failed", err.Error())
+		return
+	}
+
 	m.SetContext(InspectContext)
-	results, _ := docker.ImageInspect(id)
+	m.selected = id
+	m.showInspection(inspection)
+}
+
+// showInspection shows the top of the inspect tree, every node closed.
+func (m *Model) showInspection(inspection docker.Inspection) {
+	m.inspection = &inspection
+	m.expanded = make(map[string]bool)
+	m.view = treeView
+	m.populateInspect()
+	m.table.SetCursor(0)
+}
+
+// populateInspect lists the open nodes of the tree, or the lines of the
+// document down to the fold, the cursor staying where it was.
+func (m *Model) populateInspect() {
+	if m.view == treeView {
+		rows := docker.FlattenInspect(m.inspection.Tree, m.expanded)
+		m.setResults(docker.InspectResults(rows, m.expanded))
+		return
+	}
+	m.setResults(foldDocument(strings.ToUpper(m.view.format()), m.document, m.fold))
+}
+
+// nextView goes from the tree to the JSON to the YAML and back.
+func (m *Model) nextView(string) {
+	view := (m.view + 1) % (yamlView + 1)
+	if view != treeView {
+		doc, err := m.inspection.Document(view.format())
+		if err != nil {
+			logger.Error("table.inspector.nextView:", err)
+			m.notify("Inspect", err.Error())
+			return
+		}
+		m.document = strings.Split(strings.TrimSuffix(string(doc), "\n"), "\n")
+		m.fold = 0
+	}
+
+	m.view = view
+	m.populateInspect()
+	m.table.SetCursor(0)
+}
+
+// toggleNode opens the node at path, or closes it.
+func (m *Model) toggleNode(path string) {
+	logger.Trace(path)
+	if path == "" {
+		return
+	}
+	if m.expanded[path] {
+		delete(m.expanded, path)
+	} else {
+		m.expanded[path] = true
+	}
+	m.populateInspect()
+}
+
+func (m *Model) expandAll(string) {
+	for _, path := range docker.BranchPaths(m.inspection.Tree) {
+		m.expanded[path] = true
+	}
+	m.populateInspect()
+}
+
+func (m *Model) collapseAll(string) {
+	m.expanded = make(map[string]bool)
+	m.populateInspect()
+}
+
+func (m *Model) showPath(path string) {
+	m.notify("Path", path)
+}
+
+// foldLevel hides the deepest level of the document still shown.
+func (m *Model) foldLevel(string) {
+	if m.fold == 0 {
+		m.fold = maxLevel(m.document)
+	} else {
+		m.fold--
+	}
+	m.fold = max(m.fold, 1)
+	m.populateInspect()
+}
+
+// unfoldLevel shows one more level of the document, all of it once none
+// are left hidden.
+func (m *Model) unfoldLevel(string) {
+	if m.fold == 0 {
+		return
+	}
+	m.fold++
+	if m.fold > maxLevel(m.document) {
+		m.fold = 0
+	}
+	m.populateInspect()
+}
+
+// inspectDocument is what is inspected in the format of the view.
+func (m *Model) inspectDocument() ([]byte, error) {
+	return m.inspection.Document(m.view.format())
+}
+
+func (m *Model) writeDocument(string) {
+	logger.Trace(m.selected)
+	if m.focus == TableFocus {
+		path := docker.ShortID(m.selected) + "." + m.view.format()
+		m.focus = FormFocus
+		m.form = dialog.NewForm("Write "+strings.ToUpper(m.view.format()),
+			dialog.NewField("Path", path, "file"),
+		)
+		return
+	}
+
+	path := m.form.Value("Path")
+	if path == "" {
+		m.form.SetError(errors.New("a path is needed"))
+		return
+	}
+	doc, err := m.inspectDocument()
+	if err == nil {
+		err = os.WriteFile(path, doc, 0644)
+	}
+	if err != nil {
+		m.form.SetError(err)
+		return
+	}
+
+	m.focus = TableFocus
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	m.notify("Written", path)
+}
+
+// pageDocument pipes the document to the pager.
+func (m *Model) pageDocument(string) {
+	doc, err := m.inspectDocument()
+	if err != nil {
+		logger.Error("table.inspector.pageDocument:", err)
+		m.notify("Inspect", err.Error())
+		return
+	}
 
-	total := 0
-	columns := []bubble.Column{}
-	for i, col := range results.Columns {
-		columns = append(columns, bubble.Column{Title: col, Width: results.Width[i]})
-		total += results.Width[i]
+	cmd := pagerCommand()
+	cmd.Stdin = bytes.NewReader(doc)
+	m.pending = tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return execDoneMsg{err}
+	})
+}
+
+// editDocument opens the document in the editor, from a temporary file
+// that goes away with it.
+func (m *Model) editDocument(string) {
+	doc, err := m.inspectDocument()
+	if err != nil {
+		logger.Error("table.inspector.editDocument:", err)
+		m.notify("Inspect", err.Error())
+		return
 	}
 
-	rows := []bubble.Row{}
-	for _, r := range results.Data {
-		rows = append(rows, r)
+	f, err := os.CreateTemp("", "yadc-*."+m.view.format())
+	if err != nil {
+		logger.Error("table.inspector.editDocument:", err)
+		m.notify("Inspect", err.Error())
+		return
+	}
+	_, err = f.Write(doc)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		logger.Error("table.inspector.editDocument:", err)
+		m.notify("Inspect", err.Error())
+		return
 	}
 
-	m.table.SetData(columns, rows)
+	path := f.Name()
+	m.pending = editFile(path, func(err error) tea.Msg {
+		os.Remove(path)
+		return execDoneMsg{err}
+	})
 }
@@ -0,0 +1,71 @@
+package table
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"time"
+)
+
+// longest gap between the clicks of a double-click
+const doubleClick = 400 * time.Millisecond
+
+// openKeys are tried, in order, for the action a double-click runs.
+var openKeys = []tea.KeyMsg{
+	{Type: tea.KeyEnter},
+	{Type: tea.KeyRunes, Runes: []rune("i")},
+}
+
+// updateMouse handles a mouse event, its coordinates relative to the top
+// left corner of the table border. Clicks pick dialog buttons and rows, a
+// double-click opens the row and the rest is left to the bubble.
+func (m *Model) updateMouse(msg tea.MouseMsg) tea.Cmd {
+	click := msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft
+
+	switch m.focus {
+	case DialogFocus:
+		if !click {
+			return nil
+		}
+		x, y := overlayOrigin(m.confirm.ConfirmDialog(), baseStyle.Render(m.table.View()))
+		m.confirm.Click(m.confirm.ButtonAt(msg.X-x, msg.Y-y))
+		m.confirmDone()
+	case TableFocus:
+		if m.table.Filtering() {
+			return nil
+		}
+
+		// inside the border
+		msg.X--
+		msg.Y--
+
+		if click && m.table.HeaderAt(msg.Y) && !m.sortable() {
+			return nil
+		}
+
+		row := m.table.RowAt(msg.Y)
+		m.table, _ = m.table.Update(msg)
+		if !click || row < 0 {
+			return nil
+		}
+
+		if row == m.clickedRow && time.Since(m.lastClick) < doubleClick {
+			m.lastClick = time.Time{}
+			m.openRow()
+		} else {
+			m.lastClick = time.Now()
+			m.clickedRow = row
+		}
+	}
+
+	var cmd tea.Cmd
+	cmd, m.pending = m.pending, nil
+	return cmd
+}
+
+// openRow runs the action enter or else i runs on the selected row.
+func (m *Model) openRow() {
+	for _, msg := range openKeys {
+		if m.actionHandler(msg) {
+			return
+		}
+	}
+}
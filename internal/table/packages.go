@@ -0,0 +1,88 @@
+package table
+
+import (
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/presselam/yadc/internal/dialog"
+	"github.com/presselam/yadc/internal/docker"
+	"github.com/presselam/yadc/internal/logger"
+	"path/filepath"
+)
+
+func (m *Model) packageActions() []KeyMapping {
+	retval := []KeyMapping{
+		{cmd: (*Model).exportPackages,
+			key: key.NewBinding(
+				key.WithKeys("e"),
+				key.WithHelp("e", "export"),
+			),
+		},
+	}
+
+	return retval
+}
+
+// sbomMsg carries the package inventory of an image, read in the
+// background.
+type sbomMsg struct {
+	id   string
+	sbom docker.SBOM
+	err  error
+}
+
+// inventoryImage scans the image off the main loop, it is saved in full
+// to get at the package databases.
+func (m *Model) inventoryImage(id string) {
+	logger.Trace(id)
+	m.loading("Inventory", "Reading the packages of "+id, func() tea.Msg {
+		sbom, err := docker.ImageSBOM(id)
+		return sbomMsg{id, sbom, err}
+	})
+}
+
+func (m *Model) inventoryDone(msg sbomMsg) {
+	m.loaded()
+	if msg.err != nil {
+		logger.Error("table.packages.inventoryDone:", msg.err)
+		m.notify("Inventory Failed", msg.err.Error())
+		return
+	}
+
+	m.selected = msg.id
+	m.sbom = msg.sbom
+	m.SetContext(PackagesContext)
+	if len(msg.sbom.Packages) == 0 {
+		m.notify("Inventory", "No packages found in "+msg.id)
+	}
+}
+
+func (m *Model) populatePackages() error {
+	m.setResults(m.sbom.Results())
+	m.table.SetCursor(0)
+	return nil
+}
+
+func (m *Model) exportPackages(string) {
+	logger.Trace(m.selected)
+	if m.focus == TableFocus {
+		m.focus = FormFocus
+		m.form = dialog.NewForm("Export SBOM",
+			dialog.NewField("Format", docker.SBOMFormatSPDX, "spdx | cyclonedx"),
+			dialog.NewField("Path", docker.ShortID(m.selected)+".sbom.json", "file"),
+		)
+		return
+	}
+
+	path := m.form.Value("Path")
+	err := m.sbom.Export(m.form.Value("Format"), path)
+	if err != nil {
+		m.form.SetError(err)
+		return
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	m.notify("Export Complete", fmt.Sprintf("%d packages written to\n%s", len(m.sbom.Packages), path))
+}
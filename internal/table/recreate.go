@@ -0,0 +1,182 @@
+package table
+
+import (
+	"bytes"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/presselam/yadc/internal/dialog"
+	"github.com/presselam/yadc/internal/docker"
+	"github.com/presselam/yadc/internal/logger"
+	"os"
+)
+
+// editDoneMsg reports the editor closing on a container definition.
+type editDoneMsg struct {
+	path    string
+	replace bool
+	err     error
+}
+
+// recreateMsg reports the end of a clone or replace.
+type recreateMsg struct {
+	id      string
+	backup  string
+	replace bool
+	err     error
+}
+
+func (m *Model) cloneContainer(id string) {
+	logger.Trace(id)
+	m.editDefinition(id, false)
+}
+
+func (m *Model) recreateContainer(id string) {
+	logger.Trace(id)
+	m.editDefinition(id, true)
+}
+
+// editDefinition writes the definition of a container to a temporary file
+// and opens it in the editor.
+func (m *Model) editDefinition(id string, replace bool) {
+	def, err := docker.ContainerDefinitionOf(id)
+	if err != nil {
+		logger.Error("table.recreate.editDefinition:", err)
+		m.notify("Recreate Failed", err.Error())
+		return
+	}
+
+	header := "# Recreate " + def.Name + ": the original is stopped and kept as a backup.\n"
+	if !replace {
+		header = "# Clone " + def.Name + " as a new container.\n"
+		def.Name += "-clone"
+	}
+	header += "# Save and quit to continue, empty the file to abort.\n"
+
+	data, err := docker.EncodeDefinition(def)
+	if err != nil {
+		logger.Error("table.recreate.editDefinition:", err)
+		m.notify("Recreate Failed", err.Error())
+		return
+	}
+
+	f, err := os.CreateTemp("", "yadc-*.yaml")
+	if err != nil {
+		logger.Error("table.recreate.editDefinition:", err)
+		m.notify("Recreate Failed", err.Error())
+		return
+	}
+	_, err = f.Write(append([]byte(header), data...))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		logger.Error("table.recreate.editDefinition:", err)
+		m.notify("Recreate Failed", err.Error())
+		return
+	}
+
+	m.selected = id
+	m.pending = editDefinitionFile(f.Name(), replace)
+}
+
+func editDefinitionFile(path string, replace bool) tea.Cmd {
+	return editFile(path, func(err error) tea.Msg {
+		return editDoneMsg{path: path, replace: replace, err: err}
+	})
+}
+
+func (m *Model) editDone(msg editDoneMsg) tea.Cmd {
+	if msg.err != nil {
+		os.Remove(msg.path)
+		logger.Error("table.recreate.editDone:", msg.err)
+		m.notify("Editor Failed", msg.err.Error())
+		return nil
+	}
+
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		os.Remove(msg.path)
+		m.notify("Recreate Failed", err.Error())
+		return nil
+	}
+	if isBlank(data) {
+		os.Remove(msg.path)
+		logger.Info("Recreate aborted: ", m.selected)
+		return nil
+	}
+
+	def, err := docker.DecodeDefinition(data)
+	if err != nil {
+		// keep the file so the edits are not lost to a typo
+		m.focus = DialogFocus
+		m.confirm = dialog.NewDialog("Invalid Definition", err.Error(), "Edit", "Discard")
+		m.action = func(m *Model, _ string) {
+			m.pending = editDefinitionFile(msg.path, msg.replace)
+		}
+		// the definition holds the environment, secrets and all
+		m.dismiss = func(*Model) {
+			os.Remove(msg.path)
+		}
+		return nil
+	}
+	os.Remove(msg.path)
+
+	if !msg.replace {
+		return runRecreate(func() (string, string, error) {
+			id, err := docker.ContainerClone(def)
+			return id, "", err
+		}, false)
+	}
+
+	id := m.selected
+	m.focus = DialogFocus
+	m.confirm = dialog.NewDialog(
+		"Recreate",
+		fmt.Sprintf("This will stop %s and replace it,\nthe original is kept as a backup", id),
+		"Confirm", "Dismiss",
+	)
+	m.action = func(m *Model, _ string) {
+		m.pending = runRecreate(func() (string, string, error) {
+			return docker.ContainerReplace(id, def)
+		}, true)
+	}
+	return nil
+}
+
+func runRecreate(run func() (string, string, error), replace bool) tea.Cmd {
+	return func() tea.Msg {
+		id, backup, err := run()
+		return recreateMsg{id: id, backup: backup, replace: replace, err: err}
+	}
+}
+
+func (m *Model) recreateDone(msg recreateMsg) {
+	title := "Clone"
+	if msg.replace {
+		title = "Recreate"
+	}
+
+	if msg.err != nil {
+		logger.Error("table.recreate.recreateDone:", msg.err)
+		m.notify(title+" Failed", msg.err.Error())
+		return
+	}
+
+	m.SetContext(ContainerContext)
+	m.selectRow(docker.ShortID(msg.id))
+	if msg.replace {
+		m.notify(title, "Original kept as "+msg.backup)
+	}
+}
+
+// isBlank reports whether data holds nothing but comments and whitespace.
+func isBlank(data []byte) bool {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && !bytes.HasPrefix(line, []byte("#")) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package table
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/presselam/yadc/internal/docker"
+	"github.com/presselam/yadc/internal/logger"
+	"github.com/presselam/yadc/internal/registry"
+)
+
+func (m *Model) registryActions() []KeyMapping {
+	retval := []KeyMapping{
+		{cmd: (*Model).openRepository,
+			key: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "tags"),
+			),
+		},
+	}
+
+	return retval
+}
+
+func (m *Model) tagActions() []KeyMapping {
+	retval := []KeyMapping{
+		{cmd: (*Model).openTag,
+			key: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "manifest"),
+			),
+		},
+		{cmd: (*Model).pullTag,
+			key: key.NewBinding(
+				key.WithKeys("p"),
+				key.WithHelp("p", "pull"),
+			),
+		},
+	}
+
+	return retval
+}
+
+func (m *Model) manifestActions() []KeyMapping {
+	retval := []KeyMapping{
+		{cmd: (*Model).pullTag,
+			key: key.NewBinding(
+				key.WithKeys("p"),
+				key.WithHelp("p", "pull"),
+			),
+		},
+	}
+
+	return retval
+}
+
+// registryMsg carries what was read from the registry for context.
+type registryMsg struct {
+	context    ContextState
+	client     *registry.Client
+	repository string
+	tag        string
+	results    docker.Results
+	err        error
+}
+
+// SetRegistry connects to the registry browsed by the registry contexts,
+// switching to its repositories once they are read.
+func (m *Model) SetRegistry(host string) {
+	logger.Trace(host)
+	m.loading("Registry", "Connecting to "+host, func() tea.Msg {
+		msg := registryMsg{context: RegistryContext}
+		msg.client, msg.err = registry.New(host)
+		if msg.err == nil {
+			msg.results, msg.err = msg.client.Repositories()
+		}
+		return msg
+	})
+}
+
+func (m *Model) openRepository(id string) {
+	logger.Trace(id)
+	client := m.registry
+	m.loading("Registry", "Reading the tags of "+id, func() tea.Msg {
+		results, err := client.Tags(id)
+		return registryMsg{TagsContext, client, id, "", results, err}
+	})
+}
+
+func (m *Model) openTag(id string) {
+	logger.Trace(id)
+	client, repository := m.registry, m.repository
+	m.loading("Registry", "Reading the manifest of "+repository+":"+id, func() tea.Msg {
+		results, err := client.Manifest(repository, id)
+		return registryMsg{ManifestContext, client, repository, id, results, err}
+	})
+}
+
+// browseDone shows what was read from the registry, staying where it was
+// when that failed.
+func (m *Model) browseDone(msg registryMsg) {
+	m.loaded()
+	if msg.err != nil {
+		logger.Error("table.registry.browseDone:", msg.err)
+		m.notify("Registry Failed", msg.err.Error())
+		return
+	}
+
+	m.registry = msg.client
+	m.repository = msg.repository
+	m.tag = msg.tag
+	m.listing = msg.results
+	m.SetContext(msg.context)
+	m.table.SetCursor(0)
+}
+
+func (m *Model) pullTag(id string) {
+	logger.Trace(id)
+	if m.context == TagsContext {
+		m.tag = id
+	}
+
+	ref := m.registry.Reference(m.repository, m.tag)
+	auth := m.registry.Auth()
+	m.transfer("Pull", ref, func(func(int64)) (string, error) {
+		return docker.ImagePull(ref, auth)
+	})
+}
+
+// populateRegistry shows the listing last read from the registry.
+func (m *Model) populateRegistry() error {
+	m.setResults(m.listing)
+	return nil
+}
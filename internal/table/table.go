@@ -6,9 +6,12 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/presselam/yadc/internal/bubble"
 	"github.com/presselam/yadc/internal/dialog"
+	"github.com/presselam/yadc/internal/docker"
 	"github.com/presselam/yadc/internal/logger"
+	"github.com/presselam/yadc/internal/registry"
 	"github.com/presselam/yadc/internal/timers"
-	"sort"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -22,9 +25,21 @@ const (
 	VolumeContext    ContextState = iota
 	InspectContext   ContextState = iota
 	LogsContext      ContextState = iota
+	LayersContext    ContextState = iota
+	FilesContext     ContextState = iota
+	WasteContext     ContextState = iota
+	RegistryContext  ContextState = iota
+	TagsContext      ContextState = iota
+	ManifestContext  ContextState = iota
+	PackagesContext  ContextState = iota
+	UsageContext     ContextState = iota
+	GraphContext     ContextState = iota
 
 	TableFocus  focusState = iota
 	DialogFocus focusState = iota
+	FormFocus   focusState = iota
+	FilterFocus focusState = iota
+	PickerFocus focusState = iota
 )
 
 var (
@@ -35,20 +50,52 @@ var (
 )
 
 type Model struct {
-	focus    focusState
-	id       int
-	table    bubble.Model
-	width    int
-	context  ContextState
-	selected string
-	sorted   int
-	confirm  dialog.Model
-	action   action
+	focus      focusState
+	id         int
+	table      bubble.Model
+	width      int
+	context    ContextState
+	selected   string
+	confirm    dialog.Model
+	form       dialog.Form
+	picker     dialog.Picker
+	pending    tea.Cmd
+	offline    bool
+	action     action
+	dismiss    func(*Model)
+	analysis   docker.ImageAnalysis
+	layer      int
+	registry   *registry.Client
+	repository string
+	tag        string
+	listing    docker.Results
+	sbom       docker.SBOM
+	usage      docker.DiskUsage
+	sizes      map[string]string
+	sizedAt    time.Time
+	center     string
+	graph      []docker.GraphRow
+	inspection *docker.Inspection
+	expanded   map[string]bool
+	view       inspectView
+	document   []string
+	fold       int
+	lastClick  time.Time
+	clickedRow int
+}
+
+// execDoneMsg reports the end of a command that had taken over the
+// terminal.
+type execDoneMsg struct {
+	err error
 }
 
 type KeyMapping struct {
-	key key.Binding
-	cmd action
+	key     key.Binding
+	cmd     action
+	feature docker.Feature
+	// each, when set, lets the action run over every marked row
+	each func(id string) (string, error)
 }
 
 var sortKeys = []key.Binding{
@@ -57,18 +104,92 @@ var sortKeys = []key.Binding{
 	key.NewBinding(key.WithKeys("3")),
 	key.NewBinding(key.WithKeys("4")),
 	key.NewBinding(key.WithKeys("5")),
-	key.NewBinding(key.WithKeys("1")),
-	key.NewBinding(key.WithKeys("1")),
-	key.NewBinding(key.WithKeys("1")),
+	key.NewBinding(key.WithKeys("6")),
+	key.NewBinding(key.WithKeys("7")),
+	key.NewBinding(key.WithKeys("8")),
+	key.NewBinding(key.WithKeys("9")),
+}
+
+// defaultSort is the column a context starts out sorted on, the listings
+// of the engine go by name while the rest keep the order they come in.
+func defaultSort(context ContextState) int {
+	switch context {
+	case ContainerContext, ImageContext:
+		return 1
+	case VolumeContext:
+		return 0
+	}
+	return -1
+}
+
+// sortable reports whether the rows may be sorted, trees being in the
+// order that makes them one.
+func (m *Model) sortable() bool {
+	switch {
+	case m.context == GraphContext:
+		return false
+	case m.context == InspectContext && m.inspection != nil:
+		return false
+	}
+	return true
 }
 
-func (m Model) Focus() focusState { return m.focus }
+// columnSpecs gives, by title, how columns sort and how they give up room
+// when the terminal is too narrow for them, the highest Hide going first.
+var columnSpecs = map[string]bubble.Column{
+	"ID":         {MinWidth: 8, Flex: 1},
+	"Name":       {MinWidth: 12, Flex: 3},
+	"Image":      {MinWidth: 10, Flex: 2, Hide: 2},
+	"Ports":      {MinWidth: 10, Flex: 2, Hide: 4},
+	"Containers": {Type: bubble.ColumnInteger, Hide: 1},
+	"Count":      {Type: bubble.ColumnInteger},
+	"Layer":      {Type: bubble.ColumnInteger},
+	"Size":       {Type: bubble.ColumnBytes},
+	"Unpacked":   {Type: bubble.ColumnBytes, Hide: 2},
+	"Wasted":     {Type: bubble.ColumnBytes},
+	"Unique":     {Type: bubble.ColumnBytes},
+	"Shared":     {Type: bubble.ColumnBytes, Hide: 1},
+	"Created":    {Type: bubble.ColumnTime, Hide: 2},
+	"Tag":        {Type: bubble.ColumnVersion, MinWidth: 8, Flex: 1},
+	"Version":    {Type: bubble.ColumnVersion, MinWidth: 8, Flex: 1},
+	"Digest":     {MinWidth: 12, Flex: 1, Hide: 5},
+	"Platform":   {Hide: 3},
+	"Driver":     {Hide: 2},
+	"Scope":      {Hide: 3},
+	"Mountpoint": {MinWidth: 12, Flex: 2, Hide: 4},
+	"Created By": {MinWidth: 20, Flex: 3},
+	"Comment":    {Flex: 1, Hide: 3},
+	"Path":       {MinWidth: 20, Flex: 3},
+	"Location":   {MinWidth: 12, Flex: 2, Hide: 2},
+	"License":    {MinWidth: 8, Flex: 1, Hide: 1},
+	"Value":      {MinWidth: 20, Flex: 3},
+	"Line":       {Type: bubble.ColumnInteger, Hide: 1},
+	// documents keep their natural width, long lines are scrolled to
+	// rather than cut
+	"JSON":       {Highlight: highlightJSON},
+	"YAML":       {Highlight: highlightYAML},
+	"Logs":       {MinWidth: 20, Flex: 1},
+	"Resource":   {MinWidth: 16, Flex: 2},
+	"Relation":   {MinWidth: 8, Flex: 1, Hide: 1},
+	"Repository": {MinWidth: 12, Flex: 2},
+	"Media Type": {MinWidth: 12, Flex: 1, Hide: 2},
+}
+
+// Focus reports who has the keyboard, FilterFocus while a filter is typed
+// into the table.
+func (m Model) Focus() focusState {
+	if m.focus == TableFocus && m.table.Filtering() {
+		return FilterFocus
+	}
+	return m.focus
+}
 
 func (m Model) tick() tea.Cmd {
 	var delay time.Duration
 
 	switch m.context {
-	case InspectContext:
+	case InspectContext, LayersContext, FilesContext, WasteContext,
+		RegistryContext, TagsContext, ManifestContext, PackagesContext, UsageContext, GraphContext:
 		return nil
 	default:
 		delay = 2 * time.Second
@@ -82,7 +203,7 @@ func (m Model) tick() tea.Cmd {
 
 func (m Model) Init() tea.Cmd {
 	logger.Trace()
-	return m.tick()
+	return tea.Batch(m.tick(), reapHelpers)
 }
 
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
@@ -96,58 +217,133 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		if msg.ID == m.id {
 			// a == a  so that it repopulates the data
 			// fix it
-			m.SetContext(m.context)
+			if !m.offline {
+				m.SetContext(m.context)
+			}
 			batch = append(batch, m.tick())
 		}
+	case exportMsg:
+		return m, m.exportProgress(msg)
+	case editDoneMsg:
+		return m, m.editDone(msg)
+	case bulkMsg:
+		m.bulkDone(msg)
+		return m, nil
+	case recreateMsg:
+		m.recreateDone(msg)
+		return m, nil
+	case layersMsg:
+		m.exploreDone(msg)
+		return m, nil
+	case sbomMsg:
+		m.inventoryDone(msg)
+		return m, nil
+	case usageMsg:
+		m.usageDone(msg)
+		return m, nil
+	case registryMsg:
+		m.browseDone(msg)
+		return m, nil
+	case docker.ConnectionMsg:
+		m.offline = !msg.Connected
+		if msg.Connected {
+			// put the view back the way it was before the engine went away
+			row := m.table.SelectedRow()
+			m.SetContext(m.context)
+			if len(row) > 0 {
+				m.selectRow(row[0])
+			}
+		}
+		return m, nil
 	case tea.WindowSizeMsg:
 		m.resize(msg.Width, msg.Height)
+	case execDoneMsg:
+		if msg.err != nil {
+			logger.Error("table.exec:", msg.err)
+		}
+		m.SetContext(m.context)
+		return m, nil
+	case tea.MouseMsg:
+		return m, m.updateMouse(msg)
 	case tea.KeyMsg:
 		switch m.focus {
 		case DialogFocus:
 			switch {
 			case m.confirm.ConfirmActions(msg):
-				if m.confirm.Confirmed() {
-					logger.Info("User selected:", m.confirm.Selected())
-					if m.confirm.Selected() == 0 {
-						row := m.table.SelectedRow()
-						m.action(&m, row[0])
-					}
-					m.focus = TableFocus
-				}
-				return m, nil
+				m.confirmDone()
+				cmd, m.pending = m.pending, nil
+				return m, cmd
 			}
+		case PickerFocus:
+			m.picker, cmd = m.picker.Update(msg)
+			switch {
+			case m.picker.Cancelled():
+				m.focus = TableFocus
+			case m.picker.Submitted():
+				m.pickerDone()
+			}
+			return m, cmd
+		case FormFocus:
+			m.form, cmd = m.form.Update(msg)
+			switch {
+			case m.form.Cancelled():
+				m.focus = TableFocus
+			case m.form.Submitted():
+				m.action(&m, m.selected)
+				cmd = tea.Batch(cmd, m.pending)
+				m.pending = nil
+			}
+			return m, cmd
 		case TableFocus:
 			switch {
+			case m.table.Filtering():
 			case key.Matches(msg, KeyEscape):
+				m.table.ClearFilter()
 				return m, m.tick()
 			case m.actionHandler(msg):
-				return m, nil
+				cmd, m.pending = m.pending, nil
+				return m, cmd
 			}
 		}
 	}
 
+	marked := m.table.Marked()
 	m.table, cmd = m.table.Update(msg)
 	batch = append(batch, cmd)
+
+	// the usage totals follow the marks
+	if m.context == UsageContext && !slices.Equal(marked, m.table.Marked()) {
+		m.populateUsage()
+	}
 	return m, tea.Batch(batch...)
 }
 
 func (m Model) View() string {
 	table := baseStyle.Render(m.table.View())
 
-	if m.focus == DialogFocus {
-		confirm := m.confirm.ConfirmDialog()
-
-		return dialog.PlaceOverlay(
-			lipgloss.Width(table)/2-lipgloss.Width(confirm)/2,
-			lipgloss.Height(table)/2-lipgloss.Height(confirm)/2,
-			confirm,
-			table,
-			false,
-		)
+	switch m.focus {
+	case DialogFocus:
+		return overlay(m.confirm.ConfirmDialog(), table)
+	case PickerFocus:
+		return overlay(m.picker.View(), table)
+	case FormFocus:
+		return overlay(m.form.View(), table)
 	}
 	return table
 }
 
+// overlay places fg in the middle of bg.
+func overlay(fg string, bg string) string {
+	x, y := overlayOrigin(fg, bg)
+	return dialog.PlaceOverlay(x, y, fg, bg, false)
+}
+
+// overlayOrigin is where overlay puts the top left corner of fg.
+func overlayOrigin(fg string, bg string) (int, int) {
+	return lipgloss.Width(bg)/2 - lipgloss.Width(fg)/2,
+		lipgloss.Height(bg)/2 - lipgloss.Height(fg)/2
+}
+
 func (m *Model) resize(width int, height int) {
 	logger.Trace(width, height)
 	m.width = width - 3
@@ -155,6 +351,14 @@ func (m *Model) resize(width int, height int) {
 	m.table.SetHeight(height - 9)
 }
 
+// Pending hands over the command left to run by what the monitor started
+// rather than a key of the table, e.g. SetRegistry.
+func (m *Model) Pending() tea.Cmd {
+	cmd := m.pending
+	m.pending = nil
+	return cmd
+}
+
 func (m Model) Context() ContextState {
 	return m.context
 }
@@ -163,6 +367,12 @@ func (m *Model) SetContext(context ContextState) error {
 	logger.Trace(context)
 
 	var err error
+	if context != m.context {
+		m.table.ClearFilter()
+		m.table.ClearMarks()
+		m.table.SetSort(defaultSort(context), false)
+		m.inspection = nil
+	}
 	m.context = context
 	s := m.table.Styles()
 	switch m.context {
@@ -172,11 +382,35 @@ func (m *Model) SetContext(context ContextState) error {
 	case ImageContext:
 		err = m.PopulateImages()
 		s.Cell = ImageFormatter
+	case VolumeContext:
+		err = m.PopulateVolumes()
+		s.Cell = nil
 	case LogsContext:
 		err = m.fetchLogs()
 		s.Cell = nil
 	case InspectContext:
 		s.Cell = nil
+	case LayersContext:
+		err = m.populateLayers()
+		s.Cell = nil
+	case FilesContext:
+		err = m.populateLayerFiles()
+		s.Cell = LayerFileFormatter
+	case WasteContext:
+		err = m.populateWaste()
+		s.Cell = nil
+	case RegistryContext, TagsContext, ManifestContext:
+		err = m.populateRegistry()
+		s.Cell = nil
+	case PackagesContext:
+		err = m.populatePackages()
+		s.Cell = nil
+	case UsageContext:
+		err = m.populateUsage()
+		s.Cell = nil
+	case GraphContext:
+		err = m.populateGraph()
+		s.Cell = nil
 	}
 	m.table.SetStyles(s)
 
@@ -184,11 +418,17 @@ func (m *Model) SetContext(context ContextState) error {
 }
 
 func (m *Model) actionHandler(msg tea.KeyMsg) bool {
+	if key.Matches(msg, KeyColumns) {
+		m.pickColumns()
+		return true
+	}
+
 	// check sortkeys
 	for i, sortKey := range sortKeys {
 		if key.Matches(msg, sortKey) {
-			m.sorted = i
-			m.sortRows()
+			if m.sortable() {
+				m.table.SortBy(i)
+			}
 			return true
 		}
 	}
@@ -200,13 +440,44 @@ func (m *Model) actionHandler(msg tea.KeyMsg) bool {
 		mappings = m.containerActions()
 	case ImageContext:
 		mappings = m.imageActions()
+	case VolumeContext:
+		mappings = m.volumeActions()
+	case LayersContext:
+		mappings = m.layerActions()
+	case RegistryContext:
+		mappings = m.registryActions()
+	case TagsContext:
+		mappings = m.tagActions()
+	case ManifestContext:
+		mappings = m.manifestActions()
+	case PackagesContext:
+		mappings = m.packageActions()
+	case UsageContext:
+		mappings = m.usageActions()
+	case GraphContext:
+		mappings = m.graphActions()
+	case LogsContext:
+		mappings = m.logActions()
+	case InspectContext:
+		mappings = m.inspectActions()
 	}
 
-	row := m.table.SelectedRow()
+	var id string
+	if row := m.table.SelectedRow(); len(row) > 0 {
+		id = row[0]
+	}
 	for _, command := range mappings {
 		if key.Matches(msg, command.key) {
+			if !docker.Supports(command.feature) {
+				logger.Warn("Not supported by this engine: ", command.feature)
+				return true
+			}
+			if marked := m.table.Marked(); command.each != nil && len(marked) > 0 {
+				m.confirmBulk(command.key.Help().Desc, marked, command.each)
+				return true
+			}
 			m.action = command.cmd
-			command.cmd(m, row[0])
+			command.cmd(m, id)
 			return true
 		}
 	}
@@ -214,13 +485,74 @@ func (m *Model) actionHandler(msg tea.KeyMsg) bool {
 	return false
 }
 
-func (m *Model) sortRows() {
+// confirmDone runs the action of a dialog once it is confirmed, or the
+// dismiss hook when another button was picked.
+func (m *Model) confirmDone() {
+	if !m.confirm.Confirmed() {
+		return
+	}
+	logger.Info("User selected:", m.confirm.Selected())
+	dismiss := m.dismiss
+	m.dismiss = nil
+	switch {
+	case m.confirm.Selected() == 0 && m.action != nil:
+		var id string
+		if row := m.table.SelectedRow(); len(row) > 0 {
+			id = row[0]
+		}
+		m.action(m, id)
+	case m.confirm.Selected() != 0 && dismiss != nil:
+		dismiss(m)
+	}
+	m.focus = TableFocus
+}
+
+// notify shows a message in a dialog that only needs dismissing.
+func (m *Model) notify(title string, message string) {
+	m.focus = DialogFocus
+	m.action = nil
+	m.dismiss = nil
+	m.confirm = dialog.NewDialog(title, message, "Dismiss")
+}
 
-	rows := m.table.Rows()
-	sort.Slice(rows, func(i, j int) bool {
-		return rows[i][m.sorted] < rows[j][m.sorted]
-	})
-	m.table.SetRows(rows)
+// loading shows message while run goes off in the background, until the
+// message it returns is handed to loaded.
+func (m *Model) loading(title string, message string, run tea.Cmd) {
+	m.notify(title, message)
+	m.pending = run
+}
+
+// loaded takes down the dialog put up by loading, when it is still there.
+func (m *Model) loaded() {
+	if m.focus == DialogFocus && m.action == nil {
+		m.focus = TableFocus
+	}
+}
+
+// selectRow moves the cursor to the row whose first column is id. IDs
+// being shown trimmed in places, a short ID also finds the row of the full
+// one it starts, when nothing matches exactly.
+func (m *Model) selectRow(id string) bool {
+	if id == "" {
+		return false
+	}
+	for i, row := range m.table.Rows() {
+		if len(row) > 0 && row[0] == id {
+			m.table.SetCursor(i)
+			return true
+		}
+	}
+	// names only match whole, data-backup must not land on data
+	if strings.Trim(id, "0123456789abcdef") != "" {
+		return false
+	}
+	for i, row := range m.table.Rows() {
+		if len(row) > 0 && strings.HasPrefix(row[0], id) {
+			m.table.SetCursor(i)
+			return true
+		}
+	}
+	return false
 }
 
 func New() Model {
@@ -244,11 +576,11 @@ func New() Model {
 	t.SetStyles(s)
 
 	m := Model{
-		id:     timers.NextID(),
-		table:  t,
-		sorted: 1,
-		focus:  TableFocus,
+		id:    timers.NextID(),
+		table: t,
+		focus: TableFocus,
 	}
+	m.table.SetSort(defaultSort(m.context), false)
 
 	return m
 }
@@ -0,0 +1,65 @@
+package table
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/presselam/yadc/internal/docker"
+	"github.com/presselam/yadc/internal/logger"
+	"slices"
+)
+
+func (m *Model) usageActions() []KeyMapping {
+	retval := []KeyMapping{
+		{cmd: (*Model).clearUsage,
+			key: key.NewBinding(
+				key.WithKeys("c"),
+				key.WithHelp("c", "clear"),
+			),
+		},
+	}
+
+	return retval
+}
+
+type usageMsg struct {
+	id    string
+	usage docker.DiskUsage
+	err   error
+}
+
+func (m *Model) usageImages(id string) {
+	logger.Trace(id)
+	m.loading("Disk Usage", "Weighing the image layers", func() tea.Msg {
+		usage, err := docker.ImageDiskUsage()
+		return usageMsg{id, usage, err}
+	})
+}
+
+func (m *Model) usageDone(msg usageMsg) {
+	m.loaded()
+	if msg.err != nil {
+		logger.Error("table.usage.usageDone:", msg.err)
+		m.notify("Disk Usage Failed", msg.err.Error())
+		return
+	}
+
+	m.usage = msg.usage
+	m.table.ClearMarks()
+	m.SetContext(UsageContext)
+	m.selectRow(docker.ShortID(msg.id))
+}
+
+// populateUsage weighs the removal of the marked images.
+func (m *Model) populateUsage() error {
+	marked := slices.DeleteFunc(m.table.Marked(), func(id string) bool {
+		return id == docker.UsageTotal
+	})
+	m.setResults(m.usage.Results(marked))
+	return nil
+}
+
+func (m *Model) clearUsage(id string) {
+	logger.Trace(id)
+	m.table.ClearMarks()
+	m.populateUsage()
+}
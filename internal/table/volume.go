@@ -0,0 +1,109 @@
+package table
+
+import (
+	"errors"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/presselam/yadc/internal/dialog"
+	"github.com/presselam/yadc/internal/docker"
+	"github.com/presselam/yadc/internal/logger"
+	"path/filepath"
+	"time"
+)
+
+func (m *Model) PopulateVolumes() error {
+	results, err := docker.Volumes()
+	if err != nil {
+		return err
+	}
+
+	m.setResults(results)
+	return nil
+}
+
+// reapHelpers clears out the volume helpers an earlier run left behind.
+func reapHelpers() tea.Msg {
+	if err := docker.ReapHelpers(); err != nil {
+		logger.Error("table.volume.reapHelpers:", err)
+	}
+	return nil
+}
+
+func (m *Model) volumeActions() []KeyMapping {
+	retval := []KeyMapping{
+		{cmd: (*Model).backupVolume,
+			key: key.NewBinding(
+				key.WithKeys("B"),
+				key.WithHelp("B", "backup"),
+			),
+		},
+		{cmd: (*Model).restoreVolume,
+			key: key.NewBinding(
+				key.WithKeys("r"),
+				key.WithHelp("r", "restore"),
+			),
+		},
+	}
+
+	return retval
+}
+
+func (m *Model) backupVolume(id string) {
+	logger.Trace(id)
+	if m.focus == TableFocus {
+		path := fmt.Sprintf("%s-%s.tar.gz", id, time.Now().Format("20060102-150405"))
+		m.selected = id
+		m.focus = FormFocus
+		m.form = dialog.NewForm("Backup Volume",
+			dialog.NewField("Path", path, "file.tar.gz"),
+		)
+		return
+	}
+
+	path := m.form.Value("Path")
+	if path == "" {
+		m.form.SetError(errors.New("path is required"))
+		return
+	}
+
+	m.transfer("Backup Volume", path, func(progress func(int64)) (string, error) {
+		checksum, written, err := docker.VolumeBackup(id, path, progress)
+		if err != nil {
+			return "", err
+		}
+
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return fmt.Sprintf("%d bytes backed up to\n%s\nsha256 %s", written, path, checksum), nil
+	})
+}
+
+func (m *Model) restoreVolume(id string) {
+	logger.Trace(id)
+	if m.focus == TableFocus {
+		m.selected = id
+		m.focus = FormFocus
+		m.form = dialog.NewForm("Restore Volume",
+			dialog.NewField("Path", "", "file.tar.gz"),
+			dialog.NewField("Volume", id, "existing or new volume"),
+		)
+		return
+	}
+
+	path := m.form.Value("Path")
+	name := m.form.Value("Volume")
+	if path == "" || name == "" {
+		m.form.SetError(errors.New("path and volume are required"))
+		return
+	}
+
+	m.transfer("Restore Volume", path, func(progress func(int64)) (string, error) {
+		written, err := docker.VolumeRestore(path, name, progress)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d bytes restored into\n%s", written, name), nil
+	})
+}
@@ -2,15 +2,40 @@ package main
 
 import (
 	"flag"
+	"github.com/presselam/yadc/internal/config"
+	"github.com/presselam/yadc/internal/docker"
 	"github.com/presselam/yadc/internal/monitor"
+	"github.com/presselam/yadc/internal/table"
+	"log"
 )
 
 func main() {
 	container := flag.Bool("containers", false, "start the monitor in container mode")
 	image := flag.Bool("images", false, "start the monitor in images mode")
 	volume := flag.Bool("volumes", false, "start the monitor in volume mode")
+	detachKeys := flag.String("detach-keys", docker.DefaultDetachKeys, "key sequence for detaching from an attached container")
+	helperImage := flag.String("helper-image", docker.DefaultHelperImage, "image of the helper containers used to back up and restore volumes")
+	timezone := flag.String("timezone", "Local", "zone absolute times are shown in, e.g. UTC or Europe/Paris")
+	relativeTimes := flag.Bool("relative-times", true, "show times as \"3 days ago\" rather than as dates")
+	longIDs := flag.Bool("long-ids", false, "show IDs in full")
+	mouse := flag.Bool("mouse", true, "take mouse clicks and the wheel, false leaves the mouse to the terminal for selecting text")
+	configFile := flag.String("config", config.DefaultPath(), "file the column choices are kept in")
 	flag.Parse()
 
+	if err := config.Load(*configFile); err != nil {
+		log.Fatal(err)
+	}
+	if err := docker.SetDetachKeys(*detachKeys); err != nil {
+		log.Fatal(err)
+	}
+	if err := table.SetTimezone(*timezone); err != nil {
+		log.Fatal(err)
+	}
+	docker.SetHelperImage(*helperImage)
+	table.SetRelativeTimes(*relativeTimes)
+	table.SetLongIDs(*longIDs)
+	monitor.SetMouse(*mouse)
+
 	var mode string
 	switch {
 	case *container: